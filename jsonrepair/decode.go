@@ -0,0 +1,97 @@
+package jsonrepair
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+)
+
+// Option changes the Options used by Unmarshal and Decoder, which start from
+// DefaultOptions
+type Option func(*Options)
+
+// WithOptions replaces the options used by Unmarshal and Decoder with opts
+func WithOptions(opts Options) Option {
+	return func(o *Options) {
+		*o = opts
+	}
+}
+
+// buildOptions applies opts to DefaultOptions
+func buildOptions(opts []Option) Options {
+	o := DefaultOptions()
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// Unmarshal repairs data and decodes the result into v like json.Unmarshal
+func Unmarshal(data []byte, v any, opts ...Option) error {
+	repaired, err := appendRepair(nil, data, buildOptions(opts))
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(repaired, v)
+}
+
+// Decoder reads malformed JSON values from an input stream, repairs them on
+// the fly and decodes them like json.Decoder
+type Decoder struct {
+	dec *json.Decoder
+	s   *streamReader
+}
+
+// NewDecoder returns a Decoder that reads from r
+func NewDecoder(r io.Reader, opts ...Option) *Decoder {
+	o := buildOptions(opts)
+	// Values after the first one are decoded by later calls to Decode
+	o.TrailingContent = TrailingContentDiscard
+	s := newStreamReader(r, o)
+	s.values = true
+	return &Decoder{dec: json.NewDecoder(s), s: s}
+}
+
+// DisallowUnknownFields causes the Decoder to return an error when the
+// destination is a struct and the input contains object keys which do not
+// match any non-ignored, exported fields in the destination.
+func (d *Decoder) DisallowUnknownFields() {
+	d.dec.DisallowUnknownFields()
+}
+
+// UseNumber causes the Decoder to unmarshal a number into an interface value
+// as a json.Number instead of as a float64.
+func (d *Decoder) UseNumber() {
+	d.dec.UseNumber()
+}
+
+// Decode repairs the next JSON value from its input and stores it in the
+// value pointed to by v. It returns io.EOF when the input holds no value.
+func (d *Decoder) Decode(v any) error {
+	return d.dec.Decode(v)
+}
+
+// More reports whether there is another element in the current array or
+// object being parsed.
+func (d *Decoder) More() bool {
+	return d.dec.More()
+}
+
+// Token returns the next JSON token in the repaired input stream, like
+// json.Decoder.Token.
+func (d *Decoder) Token() (json.Token, error) {
+	return d.dec.Token()
+}
+
+// Buffered returns a reader of the repaired data remaining in the Decoder's
+// buffer. The reader is valid until the next call to Decode.
+func (d *Decoder) Buffered() io.Reader {
+	return io.MultiReader(d.dec.Buffered(), bytes.NewReader(d.s.out))
+}
+
+// InputOffset returns the offset of the current decoder position in the
+// repaired stream, like json.Decoder.InputOffset. Repairs can add or remove
+// text, so it may differ from the offset in the original input.
+func (d *Decoder) InputOffset() int64 {
+	return d.dec.InputOffset()
+}
@@ -0,0 +1,221 @@
+package jsonrepair
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+type person struct {
+	Name string `json:"name"`
+	Age  int    `json:"age"`
+}
+
+func TestUnmarshal(t *testing.T) {
+	var p person
+	if err := Unmarshal([]byte(`{name: 'John', age: 30,}`), &p); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if expected := (person{Name: "John", Age: 30}); p != expected {
+		t.Errorf("Unmarshal() = %+v, expected %+v", p, expected)
+	}
+}
+
+func TestUnmarshalOptions(t *testing.T) {
+	var v any
+	err := Unmarshal([]byte(`{"a": 1,}`), &v, func(o *Options) { o.TrailingCommas = false })
+
+	var repairErr *RepairError
+	if !errors.As(err, &repairErr) || repairErr.Kind != DisabledRepair {
+		t.Errorf("Unmarshal() error = %v, expected a DisabledRepair error", err)
+	}
+
+	if err := Unmarshal([]byte(`{"a": 1,}`), &v, WithOptions(Options{TrailingCommas: true})); err != nil {
+		t.Errorf("Unmarshal() error = %v", err)
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		setup    func(*Decoder)
+		expected any
+	}{
+		{
+			name:     "repairs on the fly",
+			input:    `{name: 'John', tags: ['a', 'b',]`,
+			expected: map[string]any{"name": "John", "tags": []any{"a", "b"}},
+		},
+		{
+			name:     "use number",
+			input:    `{count: 12345678901234567890}`,
+			setup:    (*Decoder).UseNumber,
+			expected: map[string]any{"count": json.Number("12345678901234567890")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dec := NewDecoder(strings.NewReader(tt.input))
+			if tt.setup != nil {
+				tt.setup(dec)
+			}
+
+			var v any
+			if err := dec.Decode(&v); err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if !reflect.DeepEqual(v, tt.expected) {
+				t.Errorf("Decode() = %#v, expected %#v", v, tt.expected)
+			}
+
+			if err := dec.Decode(&v); err != io.EOF {
+				t.Errorf("Decode() error = %v, expected io.EOF", err)
+			}
+		})
+	}
+}
+
+func TestDecoderMultipleValues(t *testing.T) {
+	input := "{\"a\": 1} {\"a\": 2}\n{a: 3,}\n\n[1, 2,] 'x'\n4 5\ncallback({\"a\": 6});\ncallback([7]);"
+	expected := []any{
+		map[string]any{"a": 1.0},
+		map[string]any{"a": 2.0},
+		map[string]any{"a": 3.0},
+		[]any{1.0, 2.0},
+		"x",
+		4.0,
+		5.0,
+		map[string]any{"a": 6.0},
+		[]any{7.0},
+	}
+
+	readers := map[string]func() io.Reader{
+		"whole input":    func() io.Reader { return strings.NewReader(input) },
+		"byte at a time": func() io.Reader { return iotest.OneByteReader(strings.NewReader(input)) },
+	}
+	for name, newReader := range readers {
+		t.Run(name, func(t *testing.T) {
+			dec := NewDecoder(newReader())
+
+			var values []any
+			for {
+				var v any
+				err := dec.Decode(&v)
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					t.Fatalf("Decode() error = %v", err)
+				}
+				values = append(values, v)
+			}
+
+			if !reflect.DeepEqual(values, expected) {
+				t.Errorf("decoded values = %#v, expected %#v", values, expected)
+			}
+		})
+	}
+}
+
+func TestDecoderOpenStream(t *testing.T) {
+	// A complete value is decoded before the input ends, even when nothing
+	// follows it yet
+	r, w := io.Pipe()
+	defer w.Close()
+	dec := NewDecoder(r)
+
+	values := []struct {
+		input    string
+		expected any
+	}{
+		{"{\"a\":1}\n", map[string]any{"a": 1.0}},
+		{`{"a":1}`, map[string]any{"a": 1.0}},
+		{`[1,2]`, []any{1.0, 2.0}},
+	}
+	for _, v := range values {
+		go w.Write([]byte(v.input))
+
+		decoded := make(chan error, 1)
+		var got any
+		go func() {
+			decoded <- dec.Decode(&got)
+		}()
+
+		select {
+		case err := <-decoded:
+			if err != nil {
+				t.Fatalf("Decode() after %q error = %v", v.input, err)
+			}
+			if !reflect.DeepEqual(got, v.expected) {
+				t.Errorf("Decode() after %q = %#v, expected %#v", v.input, got, v.expected)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Decode() after %q did not return before the end of input", v.input)
+		}
+	}
+}
+
+func TestDecoderBuffered(t *testing.T) {
+	dec := NewDecoder(strings.NewReader(`{a: 1} {b: 2}`))
+
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if offset := dec.InputOffset(); offset != int64(len(`{"a":1}`)) {
+		t.Errorf("InputOffset() = %d, expected %d", offset, len(`{"a":1}`))
+	}
+
+	buffered, err := io.ReadAll(dec.Buffered())
+	if err != nil {
+		t.Fatalf("reading Buffered() error = %v", err)
+	}
+	if rest := "\n{\"b\":2}\n"; !strings.HasPrefix(rest, string(buffered)) {
+		t.Errorf("Buffered() = %q, expected a prefix of %q", buffered, rest)
+	}
+}
+
+func TestDecoderDisallowUnknownFields(t *testing.T) {
+	dec := NewDecoder(strings.NewReader(`{name: 'John', email: 'john@example.com'}`))
+	dec.DisallowUnknownFields()
+
+	var p person
+	if err := dec.Decode(&p); err == nil || !strings.Contains(err.Error(), "unknown field") {
+		t.Errorf("Decode() error = %v, expected an unknown field error", err)
+	}
+}
+
+func TestDecoderEmptyInput(t *testing.T) {
+	var v any
+	if err := NewDecoder(strings.NewReader(" \n// nothing here\n")).Decode(&v); err != io.EOF {
+		t.Errorf("Decode() error = %v, expected io.EOF", err)
+	}
+}
+
+func TestDecoderTokens(t *testing.T) {
+	dec := NewDecoder(strings.NewReader(`[{id: 1}, {id: 2},]`))
+
+	if _, err := dec.Token(); err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+
+	var ids []int
+	for dec.More() {
+		var v struct{ ID int }
+		if err := dec.Decode(&v); err != nil {
+			t.Fatalf("Decode() error = %v", err)
+		}
+		ids = append(ids, v.ID)
+	}
+
+	if !reflect.DeepEqual(ids, []int{1, 2}) {
+		t.Errorf("decoded ids = %v, expected [1 2]", ids)
+	}
+}
@@ -0,0 +1,139 @@
+package jsonrepair
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrorKind identifies why the input could not be repaired
+type ErrorKind int
+
+const (
+	// UnexpectedCharacter means a character cannot start or continue a value
+	UnexpectedCharacter ErrorKind = iota
+	// UnexpectedEnd means the input ended where more text was required
+	UnexpectedEnd
+	// InvalidNumber means a number is malformed beyond repair
+	InvalidNumber
+	// InvalidKeyword means a keyword like true, false or null is misspelled
+	InvalidKeyword
+	// MissingColon means an object key is not followed by ':'
+	MissingColon
+	// InvalidMongoDBType means a MongoDB type wrapper is malformed
+	InvalidMongoDBType
+	// InvalidJSONP means a JSONP wrapper is malformed
+	InvalidJSONP
+	// DisabledRepair means the input needs a repair that is disabled in Options
+	DisabledRepair
+	// InvalidUTF8 means the input holds an invalid UTF-8 sequence outside of
+	// a string
+	InvalidUTF8
+)
+
+var errorKindNames = [...]string{
+	UnexpectedCharacter: "UnexpectedCharacter",
+	UnexpectedEnd:       "UnexpectedEnd",
+	InvalidNumber:       "InvalidNumber",
+	InvalidKeyword:      "InvalidKeyword",
+	MissingColon:        "MissingColon",
+	InvalidMongoDBType:  "InvalidMongoDBType",
+	InvalidJSONP:        "InvalidJSONP",
+	DisabledRepair:      "DisabledRepair",
+	InvalidUTF8:         "InvalidUTF8",
+}
+
+func (k ErrorKind) String() string {
+	if k >= 0 && int(k) < len(errorKindNames) {
+		return errorKindNames[k]
+	}
+	return fmt.Sprintf("ErrorKind(%d)", int(k))
+}
+
+// snippetWidth is the maximum number of bytes of the offending line shown in
+// a RepairError snippet
+const snippetWidth = 60
+
+// RepairError is returned when the input cannot be repaired. Use errors.As to
+// inspect the position of the failure.
+type RepairError struct {
+	Kind    ErrorKind
+	Message string
+	// Offset is the byte offset of the failure in the input
+	Offset int
+	// Line and Column are the 1-based position of the failure, with Column
+	// counted in runes
+	Line   int
+	Column int
+	// Rune is the offending character, or -1 at the end of input
+	Rune rune
+	// Snippet is the offending line followed by a line with a caret
+	// pointing at the failure
+	Snippet string
+}
+
+func (e *RepairError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Message, e.Offset)
+}
+
+// newRepairError builds a RepairError for the given offset in input
+func newRepairError(input string, offset int, kind ErrorKind, message string) *RepairError {
+	lineStart := strings.LastIndexByte(input[:offset], '\n') + 1
+
+	r := rune(-1)
+	if offset < len(input) {
+		r, _ = utf8.DecodeRuneInString(input[offset:])
+	}
+
+	return &RepairError{
+		Kind:    kind,
+		Message: message,
+		Offset:  offset,
+		Line:    1 + strings.Count(input[:lineStart], "\n"),
+		Column:  1 + utf8.RuneCountInString(input[lineStart:offset]),
+		Rune:    r,
+		Snippet: renderSnippet(input, lineStart, offset),
+	}
+}
+
+// renderSnippet renders the line around offset, shortened to snippetWidth,
+// with a caret below the offending character
+func renderSnippet(input string, lineStart, offset int) string {
+	lineEnd := len(input)
+	if i := strings.IndexByte(input[offset:], '\n'); i >= 0 {
+		lineEnd = offset + i
+	}
+
+	start, prefix := lineStart, ""
+	if offset-start > snippetWidth/2 {
+		start, prefix = offset-snippetWidth/2, "..."
+		for start < offset && !utf8.RuneStart(input[start]) {
+			start++
+		}
+	}
+	end, suffix := lineEnd, ""
+	if end-offset > snippetWidth/2 {
+		end, suffix = offset+snippetWidth/2, "..."
+		for end > offset && !utf8.RuneStart(input[end]) {
+			end--
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString(prefix)
+	b.WriteString(strings.TrimRight(input[start:end], "\r"))
+	b.WriteString(suffix)
+	b.WriteByte('\n')
+
+	// Keep tabs so that the caret lines up with the text above it
+	for _, r := range prefix + input[start:offset] {
+		if r == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	b.WriteByte('^')
+
+	return b.String()
+}
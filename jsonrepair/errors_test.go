@@ -0,0 +1,165 @@
+package jsonrepair
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestRepairError(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		opts    Options
+		kind    ErrorKind
+		offset  int
+		line    int
+		column  int
+		r       rune
+		snippet string
+	}{
+		{
+			name:    "unexpected character",
+			input:   "{\n  \"a\": @\n}",
+			opts:    DefaultOptions(),
+			kind:    UnexpectedCharacter,
+			offset:  9,
+			line:    2,
+			column:  8,
+			r:       '@',
+			snippet: "  \"a\": @\n       ^",
+		},
+		{
+			name:    "multi-byte characters before the failure",
+			input:   `{"é": ?}`,
+			opts:    DefaultOptions(),
+			kind:    UnexpectedCharacter,
+			offset:  7,
+			line:    1,
+			column:  7,
+			r:       '?',
+			snippet: "{\"é\": ?}\n      ^",
+		},
+		{
+			name:    "tabs are kept in the caret line",
+			input:   "[\t#]",
+			opts:    DefaultOptions(),
+			kind:    UnexpectedCharacter,
+			offset:  2,
+			line:    1,
+			column:  3,
+			r:       '#',
+			snippet: "[\t#]\n \t^",
+		},
+		{
+			name:    "end of input",
+			input:   "\n  ",
+			opts:    DefaultOptions(),
+			kind:    UnexpectedEnd,
+			offset:  3,
+			line:    2,
+			column:  3,
+			r:       -1,
+			snippet: "  \n  ^",
+		},
+		{
+			name:    "disabled truncation",
+			input:   "{\"a\": 1",
+			opts:    Options{},
+			kind:    DisabledRepair,
+			offset:  7,
+			line:    1,
+			column:  8,
+			r:       -1,
+			snippet: "{\"a\": 1\n       ^",
+		},
+		{
+			name:    "disabled repair",
+			input:   "[1,]",
+			opts:    Options{},
+			kind:    DisabledRepair,
+			offset:  2,
+			line:    1,
+			column:  3,
+			r:       ',',
+			snippet: "[1,]\n  ^",
+		},
+		{
+			name:    "invalid UTF-8 outside of a string",
+			input:   "[1, \xff]",
+			opts:    DefaultOptions(),
+			kind:    InvalidUTF8,
+			offset:  4,
+			line:    1,
+			column:  5,
+			r:       utf8.RuneError,
+			snippet: "[1, \xff]\n    ^",
+		},
+		{
+			name:    "MongoDB type",
+			input:   `ObjectId("x"`,
+			opts:    DefaultOptions(),
+			kind:    InvalidMongoDBType,
+			offset:  12,
+			line:    1,
+			column:  13,
+			r:       -1,
+			snippet: "ObjectId(\"x\"\n            ^",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := RepairWithOptions(tt.input, tt.opts)
+
+			var repairErr *RepairError
+			if !errors.As(err, &repairErr) {
+				t.Fatalf("RepairWithOptions() error = %v, expected a *RepairError", err)
+			}
+
+			if repairErr.Kind != tt.kind {
+				t.Errorf("Kind = %v, expected %v", repairErr.Kind, tt.kind)
+			}
+			if repairErr.Offset != tt.offset || repairErr.Line != tt.line || repairErr.Column != tt.column {
+				t.Errorf("position = %d (%d:%d), expected %d (%d:%d)",
+					repairErr.Offset, repairErr.Line, repairErr.Column, tt.offset, tt.line, tt.column)
+			}
+			if repairErr.Rune != tt.r {
+				t.Errorf("Rune = %q, expected %q", repairErr.Rune, tt.r)
+			}
+			if repairErr.Snippet != tt.snippet {
+				t.Errorf("Snippet = %q, expected %q", repairErr.Snippet, tt.snippet)
+			}
+		})
+	}
+}
+
+func TestRepairErrorLongLine(t *testing.T) {
+	input := "[" + strings.Repeat("1, ", 40) + "@" + strings.Repeat(", 2", 40) + "]"
+
+	_, err := Repair(input)
+
+	var repairErr *RepairError
+	if !errors.As(err, &repairErr) {
+		t.Fatalf("Repair() error = %v, expected a *RepairError", err)
+	}
+
+	lines := strings.Split(repairErr.Snippet, "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Snippet = %q, expected two lines", repairErr.Snippet)
+	}
+	if !strings.HasPrefix(lines[0], "...") || !strings.HasSuffix(lines[0], "...") {
+		t.Errorf("Snippet = %q, expected the line to be shortened on both sides", repairErr.Snippet)
+	}
+	if caret := strings.IndexByte(lines[1], '^'); lines[0][caret] != '@' {
+		t.Errorf("Snippet = %q, expected the caret below '@'", repairErr.Snippet)
+	}
+}
+
+func TestRepairErrorMessage(t *testing.T) {
+	_, err := Repair(`{"a": @}`)
+	if err == nil || err.Error() != "unexpected character '@' at position 6" {
+		t.Errorf("Repair() error = %v, expected %q", err, "unexpected character '@' at position 6")
+	}
+}
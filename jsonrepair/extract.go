@@ -0,0 +1,273 @@
+package jsonrepair
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrNotFound is returned by Extract when the input holds no JSON value
+var ErrNotFound = errors.New("no JSON value found")
+
+// Extracted is a JSON value found in surrounding text
+type Extracted struct {
+	// JSON is the repaired value
+	JSON string
+	// Start and End locate the value in the input, together with the code
+	// fence around it if there is one
+	Start int
+	End   int
+	// Language is the language tag of the code fence around the value, like
+	// json, or empty
+	Language string
+}
+
+// Extract finds the JSON value in text around it, like a model reply that
+// explains its answer, and repairs it. A code fence tagged json or without a
+// tag is preferred; otherwise the longest region of balanced braces or
+// brackets that can be repaired is used, leaving out markdown links, code
+// like x[0] and bare words in brackets like [see below].
+func Extract(input string, opts Options) (Extracted, error) {
+	all, err := ExtractAll(input, opts)
+	if err != nil {
+		return Extracted{}, err
+	}
+	if len(all) == 0 {
+		return Extracted{}, ErrNotFound
+	}
+
+	best := all[0]
+	for _, e := range all {
+		if strings.HasPrefix(input[e.Start:], "```") {
+			return e, nil
+		}
+		if e.End-e.Start > best.End-best.Start {
+			best = e
+		}
+	}
+	return best, nil
+}
+
+// ExtractAll finds and repairs every JSON value in text around them, like a
+// markdown document or a log page, in the order they appear. Values are
+// taken from code fences tagged json or without a tag, and from regions of
+// balanced braces or brackets outside of code fences, leaving out markdown
+// links, code like x[0] and bare words in brackets.
+//
+// Regions that cannot be repaired are skipped; when none of them can, the
+// error of the first one is returned.
+func ExtractAll(input string, opts Options) ([]Extracted, error) {
+	var all []Extracted
+	var firstErr error
+	for _, b := range findBlocks(input) {
+		e, ok, err := b.repair(input, opts)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if ok {
+			all = append(all, e)
+		}
+	}
+	if len(all) > 0 {
+		return all, nil
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	// A value without braces or brackets is only taken when it is the whole
+	// input
+	if trimmed := strings.TrimSpace(input); json.Valid([]byte(trimmed)) {
+		output, err := RepairWithOptions(trimmed, opts)
+		if err != nil {
+			return nil, err
+		}
+		start := strings.Index(input, trimmed)
+		all = append(all, Extracted{JSON: output, Start: start, End: start + len(trimmed)})
+	}
+	return all, nil
+}
+
+// block is a region of text that may hold a JSON value
+type block struct {
+	// start and end locate the block in the input
+	start int
+	end   int
+	// fenced is set for code fences, whose content runs from contentStart
+	// to contentEnd before the closing fence
+	fenced       bool
+	contentStart int
+	contentEnd   int
+	language     string
+	// closed is set for regions of brackets that are balanced before the
+	// end of input
+	closed bool
+}
+
+// findBlocks returns, in order, the code fences that may hold JSON and the
+// regions of balanced braces or brackets outside of code fences, other than
+// markdown links and code like x[0]
+func findBlocks(input string) []block {
+	var blocks []block
+	for i := 0; i < len(input); {
+		switch {
+		case strings.HasPrefix(input[i:], "```"):
+			b := fenceBlock(input, i)
+			if isJSONFence(input, b) {
+				blocks = append(blocks, b)
+			}
+			i = b.end
+		case input[i] == '{' || input[i] == '[':
+			b := block{start: i}
+			b.end, b.closed = matchBrackets(input, i)
+			if !isCode(input, b) && !isMarkdownLink(input, b) {
+				blocks = append(blocks, b)
+			}
+			i = b.end
+		default:
+			i++
+		}
+	}
+	return blocks
+}
+
+// fenceBlock returns the code fence starting at start, which runs to the end
+// of input when it is not closed. Like openCodeFence, its content may follow
+// the language tag on the same line, as in ```json {"a": 1}```.
+func fenceBlock(input string, start int) block {
+	b := block{start: start, fenced: true}
+
+	i := start + 3
+	for i < len(input) && (input[i] == ' ' || input[i] == '\t') {
+		i++
+	}
+	tag := i
+	for i < len(input) && isLanguageChar(input[i]) {
+		i++
+	}
+	b.language = input[tag:i]
+	b.contentStart = i
+
+	if j := strings.Index(input[i:], "```"); j >= 0 {
+		b.contentEnd = i + j
+		b.end = b.contentEnd + 3
+	} else {
+		b.contentEnd = len(input)
+		b.end = len(input)
+	}
+	return b
+}
+
+// isLanguageChar reports whether c can be part of a code fence language tag,
+// like json, json5 or c++
+func isLanguageChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '-' || c == '+' || c == '.'
+}
+
+// isJSONFence reports whether the code fence b may hold JSON: it is tagged
+// with a JSON dialect, or untagged around an object or array
+func isJSONFence(input string, b block) bool {
+	if b.language != "" {
+		return strings.Contains(strings.ToLower(b.language), "json")
+	}
+	content := strings.TrimSpace(input[b.contentStart:b.contentEnd])
+	return strings.HasPrefix(content, "{") || strings.HasPrefix(content, "[")
+}
+
+// matchBrackets returns the end of the object or array starting at start,
+// and whether it is closed before the end of input. Like the parser, it takes
+// strings in any quotes, except for apostrophes within words, like in don't.
+func matchBrackets(input string, start int) (end int, closed bool) {
+	depth := 0
+	closing := ""
+	prev := rune(0)
+	for i := start; i < len(input); {
+		r, size := utf8.DecodeRuneInString(input[i:])
+		switch {
+		case closing != "" && r == '\\':
+			size++
+		case closing != "":
+			if strings.ContainsRune(closing, r) {
+				closing = ""
+			}
+		case r == '{' || r == '[':
+			depth++
+		case r == '}' || r == ']':
+			if depth--; depth == 0 {
+				return i + size, true
+			}
+		case !isIdentRune(prev):
+			closing = closingQuotes(r)
+		}
+		prev = r
+		i += size
+	}
+	return len(input), false
+}
+
+// isCode reports whether the brackets of b follow a name, like in x[0] or
+// map{k}, which makes them code rather than JSON
+func isCode(input string, b block) bool {
+	r, _ := utf8.DecodeLastRuneInString(input[:b.start])
+	return isIdentRune(r)
+}
+
+// isMarkdownLink reports whether b is the text of a markdown link, like
+// [the docs](https://example.com)
+func isMarkdownLink(input string, b block) bool {
+	return input[b.start] == '[' && b.closed && strings.HasPrefix(input[b.end:], "(")
+}
+
+// isPlausible reports whether a region of brackets in prose, repaired with
+// actions, is likely JSON rather than text like [see below] or {name}
+func isPlausible(input string, b block, actions []Action) bool {
+	for _, a := range actions {
+		switch a.Kind {
+		case InsertedColon:
+			return false
+		case ClosedTruncatedObject, ClosedTruncatedArray, ClosedTruncatedString, AddedMissingValue, CompletedKeyword:
+			// The value must end with the balanced brackets around it
+			if b.closed {
+				return false
+			}
+		case AddedQuotes:
+			// Bare words are only taken as object keys and their values
+			if !strings.Contains(input[b.start:b.end], ":") {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// repair repairs the value in b, and reports whether it is likely JSON. The
+// parser reads the original input, up to the end of the value, so that
+// errors are located in the whole input. A code fence is parsed from its
+// content, after the language tag found by fenceBlock.
+func (b block) repair(input string, opts Options) (Extracted, bool, error) {
+	p := &parser{
+		input:  input[:b.end],
+		index:  b.start,
+		opts:   opts,
+		report: !b.fenced,
+	}
+	if b.fenced {
+		p.input = input[:b.contentEnd]
+		p.index = b.contentStart
+		p.wrapper = '`'
+	}
+	if err := p.parseRoot(); err != nil {
+		return Extracted{}, false, err
+	}
+	if !b.fenced && !isPlausible(input, b, p.actions) {
+		return Extracted{}, false, nil
+	}
+	return Extracted{
+		JSON:     p.output.String(),
+		Start:    b.start,
+		End:      b.end,
+		Language: b.language,
+	}, true, nil
+}
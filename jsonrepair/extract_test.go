@@ -0,0 +1,269 @@
+package jsonrepair
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		span     string
+		language string
+	}{
+		{
+			name:     "code fence between prose",
+			input:    "Sure! Here is the result:\n```json\n{\"a\": 1, b: [1, 2,]}\n```\nLet me know if you need more.",
+			expected: `{"a":1,"b":[1,2]}`,
+			span:     "```json\n{\"a\": 1, b: [1, 2,]}\n```",
+			language: "json",
+		},
+		{
+			name:     "untagged code fence",
+			input:    "Result:\n```\n[1, 2, 3]\n```",
+			expected: `[1,2,3]`,
+			span:     "```\n[1, 2, 3]\n```",
+		},
+		{
+			name:     "unclosed code fence",
+			input:    "```json\n{\"a\": [1, 2",
+			expected: `{"a":[1,2]}`,
+			span:     "```json\n{\"a\": [1, 2",
+			language: "json",
+		},
+		{
+			name:     "code fence of another language is skipped",
+			input:    "```python\nx = {\"a\": 1}\n```\nOutput: {\"b\": 2}",
+			expected: `{"b":2}`,
+			span:     `{"b": 2}`,
+		},
+		{
+			name:     "inline object",
+			input:    `The answer is {"x": 1}.`,
+			expected: `{"x":1}`,
+			span:     `{"x": 1}`,
+		},
+		{
+			name:     "longest inline value",
+			input:    `See [1] for {name: 'John', tags: ['a', 'b']} details`,
+			expected: `{"name":"John","tags":["a","b"]}`,
+			span:     `{name: 'John', tags: ['a', 'b']}`,
+		},
+		{
+			name:     "brackets inside strings",
+			input:    `Got {"a": "}", "b": [1]} back`,
+			expected: `{"a":"}","b":[1]}`,
+			span:     `{"a": "}", "b": [1]}`,
+		},
+		{
+			name:     "brackets inside single-quoted strings",
+			input:    `Got {'a': '}'} and [1]`,
+			expected: `{"a":"}"}`,
+			span:     `{'a': '}'}`,
+		},
+		{
+			name:     "brackets inside curly-quoted strings",
+			input:    `Got {“a”: “]”, b: [1]} back`,
+			expected: `{"a":"]","b":[1]}`,
+			span:     `{“a”: “]”, b: [1]}`,
+		},
+		{
+			name:     "apostrophe before value",
+			input:    `[don't] {"a": 1}`,
+			expected: `{"a":1}`,
+			span:     `{"a": 1}`,
+		},
+		{
+			name:     "truncated inline value",
+			input:    `Partial: {"a": [1, 2`,
+			expected: `{"a":[1,2]}`,
+			span:     `{"a": [1, 2`,
+		},
+		{
+			name:     "markdown link is skipped",
+			input:    `See [the docs](https://x.com) and {"a": 1}`,
+			expected: `{"a":1}`,
+			span:     `{"a": 1}`,
+		},
+		{
+			name:     "code and bare words are skipped",
+			input:    `Use arr[0], map{k} and [see below]: [1, 2]`,
+			expected: `[1,2]`,
+			span:     `[1, 2]`,
+		},
+		{
+			name:     "whole input",
+			input:    " 42\n",
+			expected: `42`,
+			span:     `42`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := Extract(tt.input, DefaultOptions())
+			if err != nil {
+				t.Fatalf("Extract() error = %v", err)
+			}
+
+			if e.JSON != tt.expected {
+				t.Errorf("JSON = %s, expected %s", e.JSON, tt.expected)
+			}
+			if span := tt.input[e.Start:e.End]; span != tt.span {
+				t.Errorf("span = %q, expected %q", span, tt.span)
+			}
+			if e.Language != tt.language {
+				t.Errorf("Language = %q, expected %q", e.Language, tt.language)
+			}
+		})
+	}
+}
+
+func TestExtractAll(t *testing.T) {
+	input := "# Results\n\nFirst run:\n```json\n{\"run\": 1, ok: True}\n```\n\n" +
+		"Second run:\n```jsonc\n[1, 2, // two\n3]\n```\n\n" +
+		"```go\nm := map[string]int{\"a\": 1}\n```\n\n" +
+		"Config:\n```json5\n{\"a\": 1}\n```\n\n" +
+		"Inline: ```json5 {b: 2}```\n\n" +
+		"Log: {level: 'info', msg: 'done'} and [4, 5,]\n"
+
+	expected := []struct {
+		json     string
+		span     string
+		language string
+	}{
+		{`{"run":1,"ok":true}`, "```json\n{\"run\": 1, ok: True}\n```", "json"},
+		{`[1,2,3]`, "```jsonc\n[1, 2, // two\n3]\n```", "jsonc"},
+		{`{"a":1}`, "```json5\n{\"a\": 1}\n```", "json5"},
+		{`{"b":2}`, "```json5 {b: 2}```", "json5"},
+		{`{"level":"info","msg":"done"}`, `{level: 'info', msg: 'done'}`, ""},
+		{`[4,5]`, `[4, 5,]`, ""},
+	}
+
+	all, err := ExtractAll(input, DefaultOptions())
+	if err != nil {
+		t.Fatalf("ExtractAll() error = %v", err)
+	}
+	if len(all) != len(expected) {
+		t.Fatalf("ExtractAll() = %+v, expected %d values", all, len(expected))
+	}
+
+	for i, e := range all {
+		if e.JSON != expected[i].json {
+			t.Errorf("[%d] JSON = %s, expected %s", i, e.JSON, expected[i].json)
+		}
+		if span := input[e.Start:e.End]; span != expected[i].span {
+			t.Errorf("[%d] span = %q, expected %q", i, span, expected[i].span)
+		}
+		if e.Language != expected[i].language {
+			t.Errorf("[%d] Language = %q, expected %q", i, e.Language, expected[i].language)
+		}
+	}
+}
+
+func TestExtractAllMarkdown(t *testing.T) {
+	input := "# Config migration\n\n" +
+		"See [the guide](https://example.com/guide) and the [FAQ][faq] before you start.\n" +
+		"Read values with `cfg[\"name\"]` or `items[0]`, and fill in {placeholders} later.\n\n" +
+		"Old config:\n\n```json\n{name: 'app', port: 8080,}\n```\n\n" +
+		"Run the migration:\n\n```sh\nmigrate --input old.json --filter '.items[] | {id}'\n```\n\n" +
+		"New config ([details](https://example.com/new)):\n\n```\n{\"name\": \"app\", \"server\": {\"port\": 8080}}\n```\n\n" +
+		"The tool also logs {\"status\": \"ok\", \"warnings\": []} when it is done.\n\n" +
+		"[faq]: https://example.com/faq\n"
+
+	expected := []struct {
+		json     string
+		span     string
+		language string
+	}{
+		{`{"name":"app","port":8080}`, "```json\n{name: 'app', port: 8080,}\n```", "json"},
+		{`{"name":"app","server":{"port":8080}}`, "```\n{\"name\": \"app\", \"server\": {\"port\": 8080}}\n```", ""},
+		{`{"status":"ok","warnings":[]}`, `{"status": "ok", "warnings": []}`, ""},
+	}
+
+	all, err := ExtractAll(input, DefaultOptions())
+	if err != nil {
+		t.Fatalf("ExtractAll() error = %v", err)
+	}
+	if len(all) != len(expected) {
+		t.Fatalf("ExtractAll() = %+v, expected %d values", all, len(expected))
+	}
+
+	for i, e := range all {
+		if e.JSON != expected[i].json {
+			t.Errorf("[%d] JSON = %s, expected %s", i, e.JSON, expected[i].json)
+		}
+		if span := input[e.Start:e.End]; span != expected[i].span {
+			t.Errorf("[%d] span = %q, expected %q", i, span, expected[i].span)
+		}
+		if e.Language != expected[i].language {
+			t.Errorf("[%d] Language = %q, expected %q", i, e.Language, expected[i].language)
+		}
+	}
+}
+
+func TestExtractAllSameLineFences(t *testing.T) {
+	input := "```json {\"a\": 1}``` then ```json {\"b\": 2}```"
+
+	all, err := ExtractAll(input, DefaultOptions())
+	if err != nil {
+		t.Fatalf("ExtractAll() error = %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("ExtractAll() = %+v, expected 2 values", all)
+	}
+
+	expected := []struct{ json, span string }{
+		{`{"a":1}`, "```json {\"a\": 1}```"},
+		{`{"b":2}`, "```json {\"b\": 2}```"},
+	}
+	for i, e := range expected {
+		if all[i].JSON != e.json {
+			t.Errorf("[%d] JSON = %s, expected %s", i, all[i].JSON, e.json)
+		}
+		if span := input[all[i].Start:all[i].End]; span != e.span {
+			t.Errorf("[%d] span = %q, expected %q", i, span, e.span)
+		}
+		if all[i].Language != "json" {
+			t.Errorf("[%d] Language = %q, expected json", i, all[i].Language)
+		}
+	}
+}
+
+func TestExtractAllEmpty(t *testing.T) {
+	all, err := ExtractAll("Nothing to see here.", DefaultOptions())
+	if err != nil || len(all) != 0 {
+		t.Errorf("ExtractAll() = %v, %v, expected no values", all, err)
+	}
+}
+
+func TestExtractNotFound(t *testing.T) {
+	inputs := []string{
+		"No JSON in this reply.",
+		"Use arr[0] and map{k}",
+		"Read [the docs](https://x.com) first",
+		"Fill in {name} and [see below]",
+	}
+
+	for _, input := range inputs {
+		if _, err := Extract(input, DefaultOptions()); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Extract(%q) error = %v, expected %v", input, err, ErrNotFound)
+		}
+	}
+}
+
+func TestExtractError(t *testing.T) {
+	input := "```json\n{\"a\": @}\n```"
+
+	_, err := Extract(input, DefaultOptions())
+
+	var repairErr *RepairError
+	if !errors.As(err, &repairErr) {
+		t.Fatalf("Extract() error = %v, expected a *RepairError", err)
+	}
+	if repairErr.Offset != 14 {
+		t.Errorf("Offset = %d, expected 14", repairErr.Offset)
+	}
+}
@@ -1,131 +1,572 @@
 package jsonrepair
 
 import (
+	"bytes"
 	"fmt"
+	"math/big"
+	"strconv"
 	"strings"
+	"sync"
 	"unicode"
+	"unicode/utf8"
+	"unsafe"
 )
 
 // Repair repairs a malformed JSON string and returns valid JSON
 func Repair(input string) (string, error) {
+	return RepairWithOptions(input, DefaultOptions())
+}
+
+// RepairWithOptions repairs a malformed JSON string using only the repairs
+// enabled in opts and returns valid JSON
+func RepairWithOptions(input string, opts Options) (string, error) {
 	p := &parser{
-		input:  input,
-		index:  0,
-		output: strings.Builder{},
+		input: input,
+		index: 0,
+		opts:  opts,
 	}
 	return p.parse()
 }
 
+// RepairBytes repairs malformed JSON held in a byte slice and returns valid
+// JSON in a new byte slice
+func RepairBytes(input []byte) ([]byte, error) {
+	return appendRepair(nil, input, DefaultOptions())
+}
+
+// AppendRepair repairs malformed JSON in src and appends valid JSON to dst,
+// returning the extended buffer. Reusing dst between calls avoids allocating
+// for the output; src is read in place without being copied.
+func AppendRepair(dst, src []byte) ([]byte, error) {
+	return appendRepair(dst, src, DefaultOptions())
+}
+
+// parserPool reuses parsers, and the memory of their stacks, between calls to
+// appendRepair
+var parserPool = sync.Pool{
+	New: func() any { return new(parser) },
+}
+
+func appendRepair(dst, src []byte, opts Options) ([]byte, error) {
+	p := parserPool.Get().(*parser)
+	defer func() {
+		// Drop references to the input and output before reuse
+		*p = parser{stack: p.stack[:0]}
+		parserPool.Put(p)
+	}()
+
+	*p = parser{
+		input: bytesToString(src),
+		opts:  opts,
+		stack: p.stack[:0],
+	}
+	p.output = *bytes.NewBuffer(dst)
+	if err := p.parseRoot(); err != nil {
+		return dst, err
+	}
+	return p.output.Bytes(), nil
+}
+
+// bytesToString returns a string that shares memory with b. This is safe for
+// parser input because the parser never modifies its input and copies
+// everything it keeps, including into errors.
+func bytesToString(b []byte) string {
+	return unsafe.String(unsafe.SliceData(b), len(b)) //nolint:gosec // see above
+}
+
+// doubleQuoteLike and singleQuoteLike list the quote characters that are
+// mixed up with each other, so that a string opened by one of them can be
+// closed by any other of the same kind
+const (
+	doubleQuoteLike = "\"“”„‟″‶＂"
+	singleQuoteLike = "'‘’‚‛′‵´`＇"
+)
+
+// closingQuotes returns the characters that close a string opened by open, or
+// an empty string if open is not a quote. Straight quotes and guillemets only
+// close with their own counterpart.
+func closingQuotes(open rune) string {
+	switch {
+	case open == '"':
+		return `"`
+	case open == '\'':
+		return "'"
+	case open == '«': // «
+		return "»"
+	case open == '»': // » opens strings in Danish and closes them in Swedish
+		return "«»"
+	case open == '‹': // ‹
+		return "›"
+	case open == '›':
+		return "‹›"
+	case strings.ContainsRune(doubleQuoteLike, open):
+		return doubleQuoteLike
+	case strings.ContainsRune(singleQuoteLike, open):
+		return singleQuoteLike
+	}
+	return ""
+}
+
+// hexDigits are the lower case hexadecimal digits
+const hexDigits = "0123456789abcdef"
+
+// nonFiniteLiterals lists the JavaScript and Python spellings of values that
+// JSON cannot represent
+var nonFiniteLiterals = []string{
+	"NaN", "Infinity", "+Infinity", "-Infinity", "undefined",
+	"nan", "inf", "+inf", "-inf",
+}
+
+// mongoDBTypes lists the MongoDB shell wrappers that are stripped from values
+var mongoDBTypes = []string{"NumberLong", "NumberInt", "ISODate", "ObjectId"}
+
 type parser struct {
-	input  string
-	index  int
-	output strings.Builder
+	input   string
+	index   int
+	output  bytes.Buffer
+	opts    Options
+	actions []Action
+	// report enables recording actions
+	report bool
+
+	// stack holds the containers and MongoDB types being parsed
+	stack []frame
+	// wrapper is '`' inside a code fence, '(' inside a JSONP wrapper and 0
+	// otherwise
+	wrapper byte
+	// wrapped is set once the root value was wrapped in an array together
+	// with the values following it
+	wrapped bool
+
+	// resumable enables checkpoints, used when the input is only a prefix of
+	// the document
+	resumable bool
+	// last is the most recent checkpoint, valid when last.index is not 0
+	last checkpoint
+	// peekedPastEnd is set once a decision depended on text beyond the end
+	// of input, after which no more checkpoints are taken
+	peekedPastEnd bool
+	// complete is set once the extent of the root value is known
+	complete bool
+	// rootClosed is set once a root object or array was closed by its
+	// closing bracket
+	rootClosed bool
+	// rootEnd is the end of the root value, together with the closing
+	// brackets and the end of the wrapper following it
+	rootEnd int
+	// progress is how far a string cut off by the end of input was parsed,
+	// so that parsing the same input with more text added only scans the
+	// new text
+	progress stringProgress
+}
+
+// frame is an open object ('{'), array ('[') or MongoDB type ('(')
+type frame struct {
+	open  byte
+	start int
+}
+
+// checkpoint is a position at the start of an object member, array element
+// or wrapped root value from which a new parser can continue with the same
+// result
+type checkpoint struct {
+	index   int
+	output  int
+	stack   []frame
+	first   bool
+	wrapper byte
+	wrapped bool
+}
+
+// stringProgress is a position inside a string, before its closing quote,
+// from which a new parser over the same input can continue the string with
+// the same result
+type stringProgress struct {
+	// start is the offset of the opening quote, and index the offset to
+	// continue from
+	start int
+	index int
+	// output is the repaired string up to index, after the opening quote
+	output []byte
+}
+
+// rebase makes the offsets of progress relative to the input from offset on,
+// forgetting it when the string starts before offset
+func (s *stringProgress) rebase(offset int) {
+	if s.start < offset {
+		*s = stringProgress{}
+		return
+	}
+	s.start -= offset
+	s.index -= offset
 }
 
+// stringLookahead is how far past a position inside a string the decisions
+// taken before it may have looked, like for the escape \uD83D\uDE00
+const stringLookahead = 16
+
 func (p *parser) parse() (string, error) {
+	if err := p.parseRoot(); err != nil {
+		return "", err
+	}
+	return p.output.String(), nil
+}
+
+// parseRoot parses the root value together with a code fence or JSONP
+// wrapper around it
+func (p *parser) parseRoot() error {
 	p.skipWhitespaceAndComments()
 
-	// Check for code fence like ```json ... ```
 	if p.peekCodeFence() {
-		return p.parseCodeFence()
+		// Check for code fence like ```json ... ```
+		if !p.opts.CodeFences {
+			return p.errorf(DisabledRepair, "unexpected code fence")
+		}
+		p.openCodeFence()
+	} else if p.peekFunc() && p.peekMongoDBType() == "" {
+		// Check for JSONP wrapper like callback({...})
+		if !p.opts.JSONP {
+			return p.errorf(DisabledRepair, "unexpected JSONP wrapper")
+		}
+		if err := p.openJSONPWrapper(); err != nil {
+			return err
+		}
 	}
 
-	// Check for JSONP wrapper like callback({...})
-	if p.peekFunc() {
-		return p.parseJSONPWrapper()
+	start := p.output.Len()
+	if err := p.parseValue(); err != nil {
+		return err
 	}
 
-	if err := p.parseValue(); err != nil {
-		return "", err
+	if p.opts.TrailingContent == TrailingContentArray {
+		if err := p.parseRootValues(start); err != nil {
+			return err
+		}
+	}
+
+	return p.parseRootEnd(start)
+}
+
+// parseRootValues parses the values following the root value, up to the end
+// of input or of the wrapper, and wraps them together with the root value,
+// written from start, in an array left open for parseRootEnd to close
+func (p *parser) parseRootValues(start int) error {
+	first := -1
+	for {
+		more, err := p.parseRootSeparator()
+		if err != nil {
+			return err
+		}
+		if !more {
+			break
+		}
+
+		if !p.wrapped {
+			first = p.index
+			p.insertOutput(start, '[')
+			p.wrapped = true
+		}
+		if err := p.parseRootValue(); err != nil {
+			return err
+		}
+	}
+
+	if first >= 0 {
+		p.record(WrappedRootValues, first, p.index)
+	}
+	return nil
+}
+
+// parseRootSeparator skips what separates root values, which may be
+// whitespace, a comma or nothing at all, and reports whether another value
+// follows
+func (p *parser) parseRootSeparator() (more bool, err error) {
+	p.skipWhitespaceAndComments()
+	if err := p.removeExtraBrackets(); err != nil {
+		return false, err
+	}
+
+	if p.index < len(p.input) && p.input[p.index] == ',' {
+		comma := p.index
+		p.index++
+		p.skipWhitespaceAndComments()
+		if err := p.removeExtraBrackets(); err != nil {
+			return false, err
+		}
+		if p.index >= len(p.input) || p.peekWrapperEnd() {
+			if !p.opts.TrailingCommas {
+				return false, p.errorAt(comma, DisabledRepair, "unexpected trailing comma")
+			}
+			p.record(RemovedTrailingComma, comma, comma+1)
+		}
 	}
 
+	return p.index < len(p.input) && !p.peekWrapperEnd(), nil
+}
+
+// parseRootValue parses a value following the root value once they were
+// wrapped in an array
+func (p *parser) parseRootValue() error {
+	p.checkpoint(false)
+	p.output.WriteByte(',')
+	return p.parseValue()
+}
+
+// parseRootEnd skips what follows the root value, including the end of a
+// code fence or JSONP wrapper, after which more root values may follow
+func (p *parser) parseRootEnd(start int) error {
+	// Once a root object or array is closed outside of a wrapper, what
+	// follows is discarded whatever it is
+	ended := p.index < len(p.input) || p.rootClosed && p.wrapper == 0
+	p.complete = p.opts.TrailingContent == TrailingContentDiscard && !p.peekedPastEnd && ended
+	p.rootEnd = p.index
+
 	p.skipWhitespaceAndComments()
+	if err := p.removeExtraBrackets(); err != nil {
+		return err
+	}
 
-	return p.output.String(), nil
+	if p.peekWrapperEnd() {
+		p.closeWrapper()
+		if p.opts.TrailingContent == TrailingContentArray {
+			if err := p.parseRootValues(start); err != nil {
+				return err
+			}
+		}
+		p.skipWhitespaceAndComments()
+	}
+
+	if p.wrapped {
+		p.output.WriteByte(']')
+	}
+
+	if p.index < len(p.input) {
+		if p.opts.TrailingContent == TrailingContentError {
+			return p.errorf(DisabledRepair, "unexpected content after the root value")
+		}
+		p.record(DiscardedTrailingContent, p.index, len(p.input))
+		p.index = len(p.input)
+	}
+
+	return nil
+}
+
+// closeWrapper skips the end of the code fence or JSONP wrapper at the
+// current position
+func (p *parser) closeWrapper() {
+	switch p.wrapper {
+	case '(':
+		// Skip closing parenthesis, together with the semicolon ending the
+		// JSONP statement
+		end := p.index + 1
+		if end == len(p.input) {
+			// The semicolon may still follow
+			p.peekedPastEnd = true
+			p.complete = false
+		} else if p.input[end] == ';' {
+			end++
+		}
+		p.record(StrippedJSONP, p.index, end)
+		p.index = end
+	case '`':
+		// Skip closing ```
+		p.record(StrippedCodeFence, p.index, p.index+3)
+		p.index += 3
+	}
+	p.wrapper = 0
+	p.rootEnd = p.index
+}
+
+// peekWrapperEnd reports whether the end of the code fence or JSONP wrapper
+// around the root value is at the current position
+func (p *parser) peekWrapperEnd() bool {
+	switch p.wrapper {
+	case '(':
+		return p.index < len(p.input) && p.input[p.index] == ')'
+	case '`':
+		return p.peekKeyword("```")
+	}
+	return false
+}
+
+// removeExtraBrackets removes closing brackets left after the root value.
+// Without MismatchedBrackets, they are discarded with the trailing content
+// when TrailingContent allows it, so that nothing after a complete root
+// value can fail the parse.
+func (p *parser) removeExtraBrackets() error {
+	for p.index < len(p.input) && isClosingBracket(p.input[p.index]) {
+		if !p.opts.MismatchedBrackets {
+			if p.opts.TrailingContent == TrailingContentDiscard {
+				return nil
+			}
+			return p.errorf(DisabledRepair, "unexpected '%c'", p.input[p.index])
+		}
+		p.record(RemovedClosingBracket, p.index, p.index+1)
+		p.index++
+		p.rootEnd = p.index
+		p.skipWhitespaceAndComments()
+	}
+	return nil
+}
+
+// insertOutput inserts c into the output at offset
+func (p *parser) insertOutput(offset int, c byte) {
+	p.output.WriteByte(c)
+	b := p.output.Bytes()
+	copy(b[offset+1:], b[offset:])
+	b[offset] = c
+}
+
+// resume continues parsing from a checkpoint taken by a parser over an
+// earlier prefix of the same input, rebased so that c.index refers to p.input
+func (p *parser) resume(c *checkpoint) error {
+	p.wrapper = c.wrapper
+	p.wrapped = c.wrapped
+	p.stack = append(p.stack[:0], c.stack...)
+	p.index = c.index
+
+	// Finish the innermost container first, then each enclosing one from the
+	// end of the element it was parsing
+	for i := len(c.stack) - 1; i >= 0; i-- {
+		var err error
+		switch f := c.stack[i]; {
+		case f.open == '(':
+			err = p.closeMongoDBType(f.start)
+		case i == len(c.stack)-1 && f.open == '{':
+			err = p.parseObjectMembers(c.first)
+		case i == len(c.stack)-1:
+			err = p.parseArrayElements(c.first)
+		case f.open == '{':
+			err = p.continueObject()
+		default:
+			err = p.continueArray()
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if p.wrapped {
+		// A checkpoint outside of any container is at the start of a root
+		// value
+		if len(c.stack) == 0 {
+			if err := p.parseRootValue(); err != nil {
+				return err
+			}
+		}
+		if err := p.parseRootValues(0); err != nil {
+			return err
+		}
+	}
+	return p.parseRootEnd(0)
+}
+
+// checkpoint remembers the current position, at the start of an element in
+// the innermost container or of a wrapped root value, as a place to resume
+// from with more input
+func (p *parser) checkpoint(first bool) {
+	if !p.resumable || p.peekedPastEnd || p.index >= len(p.input) {
+		return
+	}
+	// The output may still be wrapped in an array
+	if p.opts.TrailingContent == TrailingContentArray && !p.wrapped {
+		return
+	}
+	p.last = checkpoint{
+		index:   p.index,
+		output:  p.output.Len(),
+		stack:   append(p.last.stack[:0], p.stack...),
+		first:   first,
+		wrapper: p.wrapper,
+		wrapped: p.wrapped,
+	}
+}
+
+func (p *parser) push(open byte, start int) {
+	p.stack = append(p.stack, frame{open: open, start: start})
+}
+
+func (p *parser) pop() {
+	p.stack = p.stack[:len(p.stack)-1]
 }
 
 func (p *parser) parseValue() error {
 	p.skipWhitespaceAndComments()
 
 	if p.index >= len(p.input) {
-		return fmt.Errorf("unexpected end of input")
+		return p.errorf(UnexpectedEnd, "unexpected end of input")
+	}
+	if err := p.checkWhitespace(); err != nil {
+		return err
 	}
 
 	char := p.input[p.index]
 
+	// MongoDB types like ObjectId("...") or NumberLong("...")
+	if name := p.peekMongoDBType(); name != "" {
+		if !p.opts.MongoDBTypes {
+			return p.errorf(DisabledRepair, "unexpected MongoDB type %s", name)
+		}
+		return p.parseMongoDBType(name)
+	}
+
+	// NaN, Infinity, undefined and their Python spellings
+	if literal := p.peekNonFinite(); literal != "" {
+		return p.parseNonFinite(literal)
+	}
+
 	switch {
 	case char == '{':
 		return p.parseObject()
 	case char == '[':
 		return p.parseArray()
-	case char == '"':
+	case char == '"' || char == '\'':
 		return p.parseString()
-	case char == '\'':
-		return p.parseSingleQuotedString()
-	case char == 'n':
-		return p.parseKeyword("null")
-	case char == 't':
-		return p.parseKeyword("true")
-	case char == 'f':
-		return p.parseKeyword("false")
-	case char == 'N':
-		// Check for NumberLong, NumberInt, or None
-		if p.peekKeyword("NumberLong") {
-			p.index += len("NumberLong")
-			return p.parseMongoDBType()
-		} else if p.peekKeyword("NumberInt") {
-			p.index += len("NumberInt")
-			return p.parseMongoDBType()
-		} else if p.matchKeyword("None") {
-			p.output.WriteString("null")
-			return nil
-		}
-		return p.parseUnquotedString()
-	case char == 'T':
-		// Python True
-		if p.matchKeyword("True") {
-			p.output.WriteString("true")
-			return nil
-		}
-		return p.parseUnquotedString()
-	case char == 'F':
-		// Python False
-		if p.matchKeyword("False") {
-			p.output.WriteString("false")
-			return nil
-		}
-		return p.parseUnquotedString()
-	case char == 'I':
-		// MongoDB ISODate
-		if p.peekKeyword("ISODate") {
-			p.index += len("ISODate")
-			return p.parseMongoDBType()
-		}
-		return p.parseUnquotedString()
-	case char == 'O':
-		// MongoDB ObjectId
-		if p.peekKeyword("ObjectId") {
-			p.index += len("ObjectId")
-			return p.parseMongoDBType()
-		}
-		return p.parseUnquotedString()
-	case char == '-' || (char >= '0' && char <= '9'):
+	case char == 'n' || char == 't' || char == 'f' || char == 'N' || char == 'T' || char == 'F':
+		return p.parseKeyword()
+	case char == '-' || char == '+' || char == '.' || (char >= '0' && char <= '9'):
 		return p.parseNumber()
-	case unicode.IsLetter(rune(char)) || char == '_' || char == '$':
+	case char == '_' || char == '$':
+		return p.parseUnquotedString()
+	}
+
+	switch r, size := p.peekRune(); {
+	case closingQuotes(r) != "":
+		return p.parseString()
+	case unicode.IsLetter(r):
 		// Unquoted string (likely an unquoted key or special value)
 		return p.parseUnquotedString()
+	case r == utf8.RuneError && size == 1:
+		return p.errorf(InvalidUTF8, "invalid UTF-8 sequence")
 	default:
-		return fmt.Errorf("unexpected character '%c' at position %d", char, p.index)
+		return p.unexpectedCharacter()
 	}
 }
 
 func (p *parser) parseObject() error {
 	p.output.WriteByte('{')
+	p.push('{', p.index)
 	p.index++ // skip '{'
 	p.skipWhitespaceAndComments()
 
-	first := true
+	return p.parseObjectMembers(true)
+}
+
+// parseObjectMembers parses the members of an object up to and including
+// the closing brace
+func (p *parser) parseObjectMembers(first bool) error {
+	defer p.pop()
+
 	for p.index < len(p.input) && p.input[p.index] != '}' {
+		if p.input[p.index] == ']' {
+			if closed, err := p.parseMismatchedBracket(); closed || err != nil {
+				return err
+			}
+			continue
+		}
+
+		p.checkpoint(first)
+
 		if !first {
 			p.output.WriteByte(',')
 		}
@@ -138,71 +579,151 @@ func (p *parser) parseObject() error {
 			return err
 		}
 
+		keyEnd := p.index
 		p.skipWhitespaceAndComments()
 
 		// Expect colon
 		if p.index >= len(p.input) {
-			// Truncated - add closing brace
-			p.output.WriteByte('}')
-			return nil
+			// Truncated - add the missing value and closing brace
+			if err := p.closeTruncated(AddedMissingValue, ":null"); err != nil {
+				return err
+			}
+			return p.closeTruncated(ClosedTruncatedObject, "}")
 		}
 
-		if p.input[p.index] != ':' {
-			return fmt.Errorf("expected ':' at position %d", p.index)
+		if p.input[p.index] == ':' {
+			p.index++
+		} else if err := p.insertColon(keyEnd); err != nil {
+			return err
 		}
 		p.output.WriteByte(':')
-		p.index++
 
 		p.skipWhitespaceAndComments()
 
 		// Parse value
 		if p.index >= len(p.input) {
 			// Truncated - add null and close
-			p.output.WriteString("null}")
-			return nil
+			if err := p.closeTruncated(AddedMissingValue, "null"); err != nil {
+				return err
+			}
+			return p.closeTruncated(ClosedTruncatedObject, "}")
 		}
 
 		if err := p.parseValue(); err != nil {
 			return err
 		}
 
-		p.skipWhitespaceAndComments()
-
-		// Check for comma or end
-		if p.index < len(p.input) && p.input[p.index] == ',' {
-			p.index++
-			p.skipWhitespaceAndComments()
-			// Check for trailing comma
-			if p.index < len(p.input) && p.input[p.index] == '}' {
-				// Skip the comma we just saw, don't output it
-				break
-			}
+		if err := p.parseObjectSeparator(); err != nil {
+			return err
 		}
 	}
 
 	if p.index >= len(p.input) {
 		// Truncated - close the object
-		p.output.WriteByte('}')
-		return nil
+		return p.closeTruncated(ClosedTruncatedObject, "}")
 	}
 
 	p.output.WriteByte('}')
 	p.index++ // skip '}'
+	p.rootClosed = len(p.stack) == 1
+	return nil
+}
+
+// parseObjectSeparator skips the comma after an object member
+func (p *parser) parseObjectSeparator() error {
+	end := p.index
+	p.skipWhitespaceAndComments()
+	if err := p.removeUnmatchedBrackets(']'); err != nil {
+		return err
+	}
+
+	// Check for comma or end
+	if p.index < len(p.input) && p.input[p.index] == ',' {
+		comma := p.index
+		p.index++
+		p.skipWhitespaceAndComments()
+		// Check for trailing comma
+		if p.index < len(p.input) && isClosingBracket(p.input[p.index]) {
+			if !p.opts.TrailingCommas {
+				return p.errorAt(comma, DisabledRepair, "unexpected trailing comma")
+			}
+			// Skip the comma we just saw, don't output it
+			p.record(RemovedTrailingComma, comma, comma+1)
+		}
+	} else if p.index < len(p.input) && !isClosingBracket(p.input[p.index]) {
+		return p.insertComma(end)
+	}
+	return nil
+}
+
+// insertColon repairs a key followed by its value without a colon, where
+// keyEnd is the end of the key
+func (p *parser) insertColon(keyEnd int) error {
+	if err := p.checkWhitespace(); err != nil {
+		return err
+	}
+	if char := p.input[p.index]; char == ',' || char == '}' || char == ']' {
+		return p.errorf(MissingColon, "expected ':'")
+	}
+	if !p.opts.MissingSeparators {
+		return p.errorf(DisabledRepair, "expected ':'")
+	}
+	p.record(InsertedColon, keyEnd, keyEnd)
 	return nil
 }
 
+// insertComma repairs an object member or array element that follows the
+// previous one without a comma, where end is the end of the previous one.
+// The comma itself is written before the next member or element.
+func (p *parser) insertComma(end int) error {
+	if err := p.checkWhitespace(); err != nil {
+		return err
+	}
+	if !p.opts.MissingSeparators {
+		return p.errorf(DisabledRepair, "expected ','")
+	}
+	p.record(InsertedComma, end, end)
+	return nil
+}
+
+// continueObject finishes an object after the value of one of its members
+func (p *parser) continueObject() error {
+	if err := p.parseObjectSeparator(); err != nil {
+		return err
+	}
+	return p.parseObjectMembers(false)
+}
+
 func (p *parser) parseArray() error {
 	p.output.WriteByte('[')
+	p.push('[', p.index)
 	p.index++ // skip '['
 	p.skipWhitespaceAndComments()
 
-	first := true
+	return p.parseArrayElements(true)
+}
+
+// parseArrayElements parses the elements of an array up to and including
+// the closing bracket
+func (p *parser) parseArrayElements(first bool) error {
+	defer p.pop()
+
 	for p.index < len(p.input) && p.input[p.index] != ']' {
 		p.skipWhitespaceAndComments()
+		if p.index < len(p.input) && p.input[p.index] == '}' {
+			if closed, err := p.parseMismatchedBracket(); closed || err != nil {
+				return err
+			}
+			continue
+		}
+
+		p.checkpoint(first)
 
 		// Check for ellipsis (...) and skip it
-		if p.index+2 < len(p.input) && p.input[p.index:p.index+3] == "..." {
-			p.index += 3
+		if p.peekKeyword("...") {
+			if err := p.removeEllipsis(); err != nil {
+				return err
+			}
 			p.skipWhitespaceAndComments()
 			// Skip comma after ellipsis if present
 			if p.index < len(p.input) && p.input[p.index] == ',' {
@@ -216,304 +737,1431 @@ func (p *parser) parseArray() error {
 			// Continue to parse next value
 		}
 
-		if !first {
-			p.output.WriteByte(',')
+		if !first {
+			p.output.WriteByte(',')
+		}
+		first = false
+
+		if err := p.parseValue(); err != nil {
+			return err
+		}
+
+		if err := p.parseArraySeparator(); err != nil {
+			return err
+		}
+	}
+
+	if p.index >= len(p.input) {
+		// Truncated - close the array
+		return p.closeTruncated(ClosedTruncatedArray, "]")
+	}
+
+	p.output.WriteByte(']')
+	p.index++ // skip ']'
+	p.rootClosed = len(p.stack) == 1
+	return nil
+}
+
+// parseArraySeparator skips the comma after an array element, together with
+// an ellipsis following it
+func (p *parser) parseArraySeparator() error {
+	end := p.index
+	p.skipWhitespaceAndComments()
+	if err := p.removeUnmatchedBrackets('}'); err != nil {
+		return err
+	}
+
+	// Check for comma or end
+	if p.index < len(p.input) && p.input[p.index] == ',' {
+		comma := p.index
+		p.index++
+		p.skipWhitespaceAndComments()
+		// Check for trailing comma or ellipsis
+		if p.index < len(p.input) {
+			if isClosingBracket(p.input[p.index]) {
+				if !p.opts.TrailingCommas {
+					return p.errorAt(comma, DisabledRepair, "unexpected trailing comma")
+				}
+				p.record(RemovedTrailingComma, comma, comma+1)
+				return nil
+			}
+			// Check for ellipsis after comma
+			if p.peekKeyword("...") {
+				if err := p.removeEllipsis(); err != nil {
+					return err
+				}
+				p.skipWhitespaceAndComments()
+				// Check if more values follow
+				if p.index >= len(p.input) || p.input[p.index] == ']' {
+					return nil
+				}
+				// Skip comma after ellipsis if present
+				if p.input[p.index] == ',' {
+					p.index++
+					p.skipWhitespaceAndComments()
+				}
+			}
+		}
+	} else if p.index < len(p.input) && !isClosingBracket(p.input[p.index]) && p.input[p.index] != '.' {
+		// An ellipsis is removed without a comma before it
+		return p.insertComma(end)
+	}
+	return nil
+}
+
+// removeEllipsis skips the ellipsis at the current position, standing for
+// elements left out of an array
+func (p *parser) removeEllipsis() error {
+	if !p.opts.Ellipsis {
+		return p.errorf(DisabledRepair, "unexpected ellipsis")
+	}
+	p.record(RemovedEllipsis, p.index, p.index+3)
+	p.index += 3
+	return nil
+}
+
+// parseMismatchedBracket handles a closing bracket that does not match the
+// innermost object or array. When it matches an enclosing one, the innermost
+// one is closed there and closed is true. Otherwise the bracket is removed.
+func (p *parser) parseMismatchedBracket() (closed bool, err error) {
+	char := p.input[p.index]
+	if !p.opts.MismatchedBrackets {
+		return false, p.errorf(DisabledRepair, "unexpected '%c'", char)
+	}
+	if !p.closesEnclosing(char) {
+		return false, p.removeUnmatchedBrackets(char)
+	}
+
+	p.record(ClosedMismatchedBracket, p.index, p.index)
+	p.output.WriteByte(closingBracket(p.stack[len(p.stack)-1].open))
+	return true, nil
+}
+
+// removeUnmatchedBrackets removes the closing brackets char at the current
+// position that match no open object or array
+func (p *parser) removeUnmatchedBrackets(char byte) error {
+	for p.index < len(p.input) && p.input[p.index] == char && !p.closesEnclosing(char) {
+		if !p.opts.MismatchedBrackets {
+			return p.errorf(DisabledRepair, "unexpected '%c'", char)
+		}
+		p.record(RemovedClosingBracket, p.index, p.index+1)
+		p.index++
+		p.skipWhitespaceAndComments()
+	}
+	return nil
+}
+
+// closesEnclosing reports whether the closing bracket char matches an object
+// or array enclosing the innermost one
+func (p *parser) closesEnclosing(char byte) bool {
+	open := byte('{')
+	if char == ']' {
+		open = '['
+	}
+	// Brackets cannot close past a MongoDB type
+	for i := len(p.stack) - 2; i >= 0 && p.stack[i].open != '('; i-- {
+		if p.stack[i].open == open {
+			return true
+		}
+	}
+	return false
+}
+
+// closingBracket returns the bracket that closes open
+func closingBracket(open byte) byte {
+	if open == '[' {
+		return ']'
+	}
+	return '}'
+}
+
+func isClosingBracket(c byte) bool {
+	return c == '}' || c == ']'
+}
+
+// continueArray finishes an array after one of its elements
+func (p *parser) continueArray() error {
+	if err := p.parseArraySeparator(); err != nil {
+		return err
+	}
+	return p.parseArrayElements(false)
+}
+
+func (p *parser) parseKey() error {
+	if p.index >= len(p.input) {
+		return p.errorf(UnexpectedEnd, "unexpected end of input while parsing key")
+	}
+	if err := p.checkWhitespace(); err != nil {
+		return err
+	}
+
+	if r, _ := p.peekQuote(); r != 0 {
+		return p.parseString()
+	}
+
+	// Unquoted key - need to add quotes
+	return p.parseUnquotedKey()
+}
+
+// parseString parses a string delimited by straight, typographic, prime or
+// full-width quotes, together with strings concatenated to it with +
+func (p *parser) parseString() error {
+	if err := p.parseQuotedString(false, -1); err != nil {
+		return err
+	}
+	return p.parseConcatenatedStrings()
+}
+
+// parseQuotedString parses a single quoted string. When its closing quote
+// is missing, the string is parsed again either with stopAtDelimiter set, to
+// end it at the first delimiter, or with stopAtIndex set, to end it at that
+// offset.
+func (p *parser) parseQuotedString(stopAtDelimiter bool, stopAtIndex int) error {
+	open, size := p.peekRune()
+	if err := p.checkQuote(open); err != nil {
+		return err
+	}
+	closing := closingQuotes(open)
+	s := stringStart{index: p.index, output: p.output.Len(), actions: len(p.actions)}
+
+	if open != '"' {
+		p.record(ReplacedQuotes, p.index, p.index+size)
+	}
+	p.output.WriteByte('"')
+	p.index += size // skip opening quote
+	s.content = p.index
+
+	// Without a closing quote, the string is tracked to continue it from
+	// where it was cut off when more of the input is parsed again
+	t := p.resumeString(s, stopAtIndex < 0 && !stopAtDelimiter)
+	defer t.finish(p)
+	literal := p.opts.Escapes && p.isDrivePath(s.content)
+
+	for {
+		t.mark(p)
+		if p.index >= len(p.input) {
+			return p.endString(s, stopAtDelimiter, &t)
+		}
+		if p.index == stopAtIndex {
+			p.addClosingQuote()
+			return nil
+		}
+
+		r, _ := p.peekRune()
+		switch {
+		case strings.ContainsRune(closing, r):
+			if done, err := p.parseStringQuote(s, closing, stopAtDelimiter); done || err != nil {
+				return err
+			}
+		case stopAtDelimiter && p.isStringDelimiter():
+			p.closeAtDelimiter(s.content, r)
+			return nil
+		default:
+			if err := p.parseStringChar(r, literal); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// stringStart is where parseQuotedString started a string, to roll back to
+// when the string is parsed again
+type stringStart struct {
+	index   int
+	output  int
+	actions int
+	// content is the offset after the opening quote
+	content int
+}
+
+// endString ends a string cut off by the end of input
+func (p *parser) endString(s stringStart, stopAtDelimiter bool, t *stringTracker) error {
+	// A closing quote might still follow
+	p.peekedPastEnd = true
+
+	if p.opts.MissingQuotes && !stopAtDelimiter && isDelimiter(p.input[p.prevNonWhitespace(s.content, p.index)]) {
+		// The string ends with a delimiter, like ["hello], so the closing
+		// quote belongs before the first delimiter
+		p.rollback(s.index, s.output, s.actions)
+		return p.parseQuotedString(true, -1)
+	}
+
+	t.save(p)
+	// Unterminated string - close it
+	return p.closeTruncated(ClosedTruncatedString, `"`)
+}
+
+// parseStringQuote parses a closing quote at the current position of a
+// string. It reports whether the string ended, which may be after parsing it
+// again to close it where its closing quote most likely belongs.
+func (p *parser) parseStringQuote(s stringStart, closing string, stopAtDelimiter bool) (done bool, err error) {
+	r, size := p.peekRune()
+	quote := p.index
+	p.index += size
+
+	if !p.opts.MissingQuotes || stopAtDelimiter || p.isStringEnd() {
+		if r != '"' {
+			p.record(ReplacedQuotes, quote, p.index)
+		}
+		p.output.WriteByte('"')
+		return true, nil
+	}
+
+	// The quote is not followed by what can follow a string, so it is either
+	// the opening quote of the next string or a quote inside this one
+	prev := p.prevNonWhitespace(s.content, quote)
+	switch {
+	case p.input[prev] == ',':
+		// A comma followed by a quote, like {"a": "b, "c": 1}. The closing
+		// quote belongs before the comma.
+		p.rollback(s.index, s.output, s.actions)
+		return true, p.parseQuotedString(false, prev)
+	case isDelimiter(p.input[prev]):
+		// The closing quote belongs before the first delimiter
+		p.rollback(s.index, s.output, s.actions)
+		return true, p.parseQuotedString(true, -1)
+	case p.isKeyAhead(closing):
+		// The quote opens the next key, like in {"a": "b\n"c": 1}. The
+		// closing quote belongs after the text before it.
+		p.rollback(s.index, s.output, s.actions)
+		return true, p.parseQuotedString(false, prev+1)
+	}
+
+	// Quote inside the string
+	p.record(EscapedQuote, quote, p.index)
+	if r == '"' {
+		p.output.WriteString(`\"`)
+	} else {
+		p.output.WriteString(p.input[quote:p.index])
+	}
+	return false, nil
+}
+
+// closeAtDelimiter closes a string with a missing closing quote at the
+// delimiter r at the current position, with the content of the string
+// starting at contentStart
+func (p *parser) closeAtDelimiter(contentStart int, r rune) {
+	if r == '/' && isURLScheme(p.input[contentStart:p.index]) {
+		// Keep a URL like "https://... instead of treating // as the start
+		// of a comment
+		for p.index < len(p.input) && isURLChar(p.input[p.index]) {
+			p.output.WriteByte(p.input[p.index])
+			p.index++
+		}
+		if p.index >= len(p.input) {
+			p.peekedPastEnd = true
+		}
+	}
+	p.addClosingQuote()
+}
+
+// parseStringChar copies the character r at the current position of a string
+// to the output, together with the rest of an escape sequence starting there
+func (p *parser) parseStringChar(r rune, literal bool) error {
+	switch {
+	case r == '\\':
+		return p.parseEscape(literal)
+	case r == '"':
+		// Double quote inside a string delimited by other quotes needs to be
+		// escaped
+		p.record(EscapedQuote, p.index, p.index+1)
+		p.output.WriteString(`\"`)
+		p.index++
+	case r >= ' ' && r < utf8.RuneSelf:
+		p.output.WriteByte(byte(r))
+		p.index++
+	default:
+		return p.copyRune()
+	}
+	return nil
+}
+
+// stringTracker follows the last position inside a string from which a new
+// parser over the same input, with more text added, can continue the string
+type stringTracker struct {
+	on       bool
+	start    int
+	output   int
+	progress stringProgress
+	// safe is the last position to continue from, and safeOutput the length
+	// of the output there
+	safe       int
+	safeOutput int
+	// peeked is peekedPastEnd before the string, since only decisions taken
+	// in the string tell whether it can be continued
+	peeked bool
+}
+
+// resumeString starts tracking the string started at s when on is set and
+// the input may be continued, and continues it from the progress of an
+// earlier parser over the same input
+func (p *parser) resumeString(s stringStart, on bool) stringTracker {
+	t := stringTracker{on: on && p.resumable, start: s.index, output: s.output, safe: -1}
+	if !t.on {
+		return t
+	}
+	t.peeked = p.peekedPastEnd
+	p.peekedPastEnd = false
+
+	if p.progress.start == s.index && p.progress.index > s.index {
+		t.progress = p.progress
+		p.output.Write(t.progress.output)
+		p.index = t.progress.index
+	}
+	return t
+}
+
+// mark remembers the current position as a place to continue the string
+// from, unless a decision taken so far may change with more input
+func (t *stringTracker) mark(p *parser) {
+	if t.on && !p.peekedPastEnd && p.index+stringLookahead <= len(p.input) {
+		t.safe, t.safeOutput = p.index, p.output.Len()
+	}
+}
+
+// save records the last marked position as the progress of the parser
+func (t *stringTracker) save(p *parser) {
+	if t.safe <= t.start {
+		return
+	}
+	// Only the output after the progress continued from is new
+	output := p.output.Bytes()[t.output+1 : t.safeOutput]
+	p.progress = stringProgress{
+		start:  t.start,
+		index:  t.safe,
+		output: append(t.progress.output, output[len(t.progress.output):]...),
+	}
+}
+
+// finish restores peekedPastEnd once the string was parsed
+func (t *stringTracker) finish(p *parser) {
+	p.peekedPastEnd = p.peekedPastEnd || t.peeked
+}
+
+// parseConcatenatedStrings merges strings joined to the string that was just
+// parsed with + into it
+func (p *parser) parseConcatenatedStrings() error {
+	for {
+		end := p.index
+		if r, size := utf8.DecodeLastRuneInString(p.input[:p.index]); closingQuotes(r) != "" {
+			end -= size
+		}
+
+		savedIndex, savedActions := p.index, len(p.actions)
+		p.skipWhitespaceAndComments()
+		if p.index >= len(p.input) {
+			// A + might still follow
+			p.peekedPastEnd = true
+		}
+		if p.index < len(p.input) && p.input[p.index] == '+' {
+			plus := p.index
+			p.index++
+			p.skipWhitespaceAndComments()
+			if p.index >= len(p.input) {
+				p.peekedPastEnd = true
+			}
+			if next, size := p.peekQuote(); next != 0 {
+				if !p.opts.StringConcatenation {
+					return p.errorAt(plus, DisabledRepair, "unexpected string concatenation")
+				}
+				p.record(ConcatenatedStrings, end, p.index+size)
+
+				// Drop the closing quote of the string before and the
+				// opening quote of the next one
+				p.output.Truncate(p.output.Len() - 1)
+				opening := p.output.Len()
+				if err := p.parseQuotedString(false, -1); err != nil {
+					return err
+				}
+				b := p.output.Bytes()
+				copy(b[opening:], b[opening+1:])
+				p.output.Truncate(len(b) - 1)
+				continue
+			}
+		}
+
+		// No concatenation, restore index
+		p.index = savedIndex
+		p.actions = p.actions[:savedActions]
+		return nil
+	}
+}
+
+// rollback returns to an earlier position to parse the input again
+func (p *parser) rollback(index, output, actions int) {
+	p.index = index
+	p.output.Truncate(output)
+	p.actions = p.actions[:actions]
+}
+
+// addClosingQuote closes a string with a missing closing quote at the current
+// position, leaving whitespace before it out of the string
+func (p *parser) addClosingQuote() {
+	b := p.output.Bytes()
+	n := len(b)
+	for n > 0 && b[n-1] == ' ' {
+		n--
+	}
+	p.output.Truncate(n)
+	p.record(AddedClosingQuote, p.index, p.index)
+	p.output.WriteByte('"')
+}
+
+// isStringEnd reports whether the text after a closing quote shows that the
+// string really ends there: the end of input, a delimiter, or the start of
+// another string or of a number
+func (p *parser) isStringEnd() bool {
+	savedIndex, savedActions := p.index, len(p.actions)
+	defer func() {
+		p.index = savedIndex
+		p.actions = p.actions[:savedActions]
+	}()
+
+	p.skipWhitespaceAndComments()
+	if p.index >= len(p.input) {
+		p.peekedPastEnd = true
+		return true
+	}
+	r, _ := p.peekRune()
+	return (r < utf8.RuneSelf && isDelimiter(byte(r))) || closingQuotes(r) != "" || (r >= '0' && r <= '9')
+}
+
+// isKeyAhead reports whether the text from the current position up to the
+// end of the line is a key delimited by closing, followed by a colon
+func (p *parser) isKeyAhead(closing string) bool {
+	i := p.index
+	for i < len(p.input) && p.input[i] != '\n' {
+		r, size := utf8.DecodeRuneInString(p.input[i:])
+		i += size
+		if strings.ContainsRune(closing, r) {
+			for i < len(p.input) && (p.input[i] == ' ' || p.input[i] == '\t') {
+				i++
+			}
+			break
+		}
+	}
+	if i >= len(p.input) {
+		p.peekedPastEnd = true
+		return false
+	}
+	return p.input[i] == ':'
+}
+
+// isStringDelimiter reports whether a string with a missing closing quote
+// ends at the current position
+func (p *parser) isStringDelimiter() bool {
+	switch p.input[p.index] {
+	case ',', '[', ']', '{', '}', '\n':
+		return true
+	case '/':
+		// The start of a comment
+		if p.index+1 >= len(p.input) {
+			p.peekedPastEnd = true
+			return false
+		}
+		next := p.input[p.index+1]
+		return next == '/' || next == '*'
+	}
+	return false
+}
+
+// prevNonWhitespace returns the offset of the last character before end that
+// is not whitespace, or start-1 if there is none after start
+func (p *parser) prevNonWhitespace(start, end int) int {
+	i := end - 1
+	for i >= start && (p.input[i] == ' ' || p.input[i] == '\t' || p.input[i] == '\n' || p.input[i] == '\r') {
+		i--
+	}
+	return i
+}
+
+// isDelimiter reports whether c separates or closes values
+func isDelimiter(c byte) bool {
+	return strings.IndexByte(",:[]/{}()\n+", c) >= 0
+}
+
+// isURLScheme reports whether s is the scheme of a URL, like "https:"
+func isURLScheme(s string) bool {
+	switch s {
+	case "http:", "https:", "ftp:", "mailto:", "file:", "data:", "irc:", "s3:":
+		return true
+	}
+	return false
+}
+
+// isURLChar reports whether c can be part of a URL
+func isURLChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		strings.IndexByte("-._~:/?#@!$&'()*+;=%", c) >= 0
+}
+
+// parseEscape copies the escape sequence at the current position to the
+// output, repairing escapes that JSON does not allow. With literal set, the
+// string is a Windows path like C:\temp\new, so \b, \f, \n, \r and \t are
+// taken as a backslash and a letter too.
+func (p *parser) parseEscape(literal bool) error {
+	start := p.index
+	p.index++ // skip backslash
+
+	if p.index >= len(p.input) {
+		// Escape cut off by the end of input - drop it
+		return p.repairEscape(start)
+	}
+
+	r, size := p.peekRune()
+	switch {
+	case literal && strings.ContainsRune("bfnrt", r):
+		// Backslash in a path like "C:\path\file" - escape it and keep
+		// the letter after it
+		p.index++
+		if err := p.repairEscape(start); err != nil {
+			return err
+		}
+		p.output.WriteString(`\\`)
+		p.output.WriteRune(r)
+		return nil
+	case strings.ContainsRune(`"\/bfnrt`, r):
+		p.index++
+		p.output.WriteString(p.input[start:p.index])
+		return nil
+	case r == 'u':
+		return p.parseUnicodeEscape(start)
+	case r == 'x' && countHexDigits(p.input[p.index+1:], 2) == 2:
+		// Hexadecimal escape like \x41
+		p.index += 3
+		if err := p.repairEscape(start); err != nil {
+			return err
+		}
+		p.output.WriteString(`\u00`)
+		p.output.WriteString(p.input[p.index-2 : p.index])
+		return nil
+	case r >= '0' && r <= '7':
+		// Octal escape like \101 or \0
+		value := int(r - '0')
+		p.index++
+		for i := 1; i < 3 && p.index < len(p.input); i++ {
+			c := p.input[p.index]
+			if c < '0' || c > '7' || value*8+int(c-'0') > 0xff {
+				break
+			}
+			value = value*8 + int(c-'0')
+			p.index++
+		}
+		if err := p.repairEscape(start); err != nil {
+			return err
+		}
+		p.output.WriteString(`\u00`)
+		p.output.WriteByte(hexDigits[value>>4])
+		p.output.WriteByte(hexDigits[value&0xf])
+		return nil
+	case closingQuotes(r) != "":
+		// Quotes other than " need no escape
+		p.index += size
+		if err := p.repairEscape(start); err != nil {
+			return err
+		}
+		p.output.WriteString(p.input[start+1 : p.index])
+		return nil
+	}
+
+	// Stray backslash, like in a Windows path - escape it and keep the
+	// character after it
+	if err := p.repairEscape(start); err != nil {
+		return err
+	}
+	p.output.WriteString(`\\`)
+	return nil
+}
+
+// isDrivePath reports whether the string with content from start begins
+// with a Windows drive, like C:\, so that its backslashes are taken as path
+// separators
+func (p *parser) isDrivePath(start int) bool {
+	rest := p.input[start:]
+	if len(rest) < 3 {
+		// A drive might still follow
+		if rest == "" || isASCIILetter(rest[0]) && strings.HasPrefix(`:\`, rest[1:]) {
+			p.peekedPastEnd = true
+		}
+		return false
+	}
+	return isASCIILetter(rest[0]) && rest[1:3] == `:\`
+}
+
+// isASCIILetter reports whether c is an ASCII letter
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// parseUnicodeEscape copies the \u escape starting at start to the output,
+// with the current position at the 'u'
+func (p *parser) parseUnicodeEscape(start int) error {
+	p.index++ // skip 'u'
+
+	if n := countHexDigits(p.input[p.index:], 4); n < 4 {
+		if p.index+n >= len(p.input) {
+			// Escape cut off by the end of input - drop it
+			p.index += n
+			return p.repairEscape(start)
+		}
+		// Not an escape - escape the backslash and keep the text
+		if err := p.repairEscape(start); err != nil {
+			return err
+		}
+		p.output.WriteString(`\\u`)
+		return nil
+	}
+
+	code, _ := strconv.ParseUint(p.input[p.index:p.index+4], 16, 16)
+	p.index += 4
+
+	if code >= 0xd800 && code < 0xdc00 {
+		// High surrogate, which must be followed by a low surrogate
+		if rest := p.input[p.index:]; strings.HasPrefix(rest, `\u`) && countHexDigits(rest[2:], 4) == 4 {
+			if low, _ := strconv.ParseUint(rest[2:6], 16, 16); low >= 0xdc00 && low < 0xe000 {
+				p.index += 6
+				p.output.WriteString(p.input[start:p.index])
+				return nil
+			}
+		}
+	} else if code < 0xdc00 || code >= 0xe000 {
+		p.output.WriteString(p.input[start:p.index])
+		return nil
+	}
+
+	// Lone surrogate - replace it with U+FFFD
+	if err := p.repairEscape(start); err != nil {
+		return err
+	}
+	p.output.WriteRune(utf8.RuneError)
+	return nil
+}
+
+// repairEscape records the repair of the escape sequence from start to the
+// current position, or fails when escape repair is disabled
+func (p *parser) repairEscape(start int) error {
+	if !p.opts.Escapes {
+		return p.errorAt(start, DisabledRepair, "invalid escape sequence")
+	}
+	p.record(RepairedEscape, start, p.index)
+	return nil
+}
+
+// countHexDigits counts the hexadecimal digits at the start of s, up to limit
+func countHexDigits(s string, limit int) int {
+	n := 0
+	for n < limit && n < len(s) && strings.IndexByte(hexDigits+"ABCDEF", s[n]) >= 0 {
+		n++
+	}
+	return n
+}
+
+// peekQuote returns the quote character at the current position and its
+// size, or 0 if there is none
+func (p *parser) peekQuote() (rune, int) {
+	if p.index >= len(p.input) {
+		return 0, 0
+	}
+	r, size := p.peekRune()
+	if closingQuotes(r) == "" {
+		return 0, 0
+	}
+	return r, size
+}
+
+// checkQuote fails if strings delimited by open are disabled in the options
+func (p *parser) checkQuote(open rune) error {
+	switch {
+	case open == '"':
+		return nil
+	case open == '\'':
+		if !p.opts.SingleQuotes {
+			return p.errorf(DisabledRepair, "unexpected single quoted string")
+		}
+	case !p.opts.SpecialQuotes:
+		return p.errorf(DisabledRepair, "unexpected quote character '%c'", open)
+	}
+	return nil
+}
+
+func (p *parser) parseUnquotedKey() error {
+	if p.opts.MissingQuotes {
+		if end := p.findClosingQuote(true); end >= 0 {
+			return p.parseMissingOpeningQuote(end)
+		}
+	}
+	if !p.opts.UnquotedStrings {
+		return p.errorf(DisabledRepair, "unexpected unquoted key")
+	}
+
+	start := p.index
+	url := p.urlEnd(start)
+	p.output.WriteByte('"')
+
+	// Read until we hit a colon, whitespace, or comment. A colon followed by
+	// // is part of a URL like https://example.com
+	for p.index < len(p.input) {
+		r, _ := p.peekRune()
+		if r == ':' && !p.isURLSeparator(p.index) {
+			break
+		}
+		if unicode.IsSpace(r) {
+			break
+		}
+		if p.index >= url && p.isCommentStart(p.index) {
+			if !p.opts.Comments {
+				return p.errorf(DisabledRepair, "unexpected comment")
+			}
+			break
+		}
+		if err := p.copyUnquotedRune(); err != nil {
+			return err
+		}
+	}
+
+	p.record(AddedQuotes, start, p.index)
+	p.output.WriteByte('"')
+
+	return nil
+}
+
+func (p *parser) parseUnquotedString() error {
+	// This handles unquoted strings that should be quoted
+	// We quote them as strings
+	if p.opts.MissingQuotes {
+		if end := p.findClosingQuote(false); end >= 0 {
+			return p.parseMissingOpeningQuote(end)
+		}
+	}
+	if !p.opts.UnquotedStrings {
+		return p.errorf(DisabledRepair, "unexpected unquoted string")
+	}
+
+	start := p.index
+	url := p.urlEnd(start)
+
+	// Read until the next delimiter, leaving out whitespace before it
+	end := p.index
+	for i := p.index; i < len(p.input); {
+		c := p.input[i]
+		if c == ',' || c == '}' || c == ']' || c == '\n' {
+			break
+		}
+		if i >= url && p.isCommentStart(i) {
+			if !p.opts.Comments {
+				return p.errorAt(i, DisabledRepair, "unexpected comment")
+			}
+			break
+		}
+		r, size := utf8.DecodeRuneInString(p.input[i:])
+		i += size
+		if !unicode.IsSpace(r) {
+			end = i
+		}
+		if i >= len(p.input) {
+			// The value might go on
+			p.peekedPastEnd = true
+		}
+	}
+
+	p.output.WriteByte('"')
+	for p.index < end {
+		if err := p.copyUnquotedRune(); err != nil {
+			return err
+		}
+	}
+
+	p.record(AddedQuotes, start, p.index)
+	p.output.WriteByte('"')
+
+	return nil
+}
+
+// isCommentStart reports whether a comment starts at offset i in an unquoted
+// key or value. A // after a colon is part of a URL like https://, and /* only
+// starts a comment after whitespace, so that paths like src/*.go are kept.
+func (p *parser) isCommentStart(i int) bool {
+	if p.input[i] != '/' {
+		return false
+	}
+	if i+1 >= len(p.input) {
+		p.peekedPastEnd = true
+		return false
+	}
+	prev, _ := utf8.DecodeLastRuneInString(p.input[:i])
+	switch p.input[i+1] {
+	case '/':
+		return prev != ':'
+	case '*':
+		return unicode.IsSpace(prev)
+	}
+	return false
+}
+
+// urlEnd returns the end of the URL starting at offset start, like
+// https://example.com/a//b, in which // starts no comment, or -1 if there is
+// none
+func (p *parser) urlEnd(start int) int {
+	i := start
+	for i < len(p.input) && (isASCIILetter(p.input[i]) || p.input[i] >= '0' && p.input[i] <= '9') {
+		i++
+	}
+	if i >= len(p.input) || p.input[i] != ':' || !isURLScheme(p.input[start:i+1]) || !strings.HasPrefix(p.input[i+1:], "//") {
+		return -1
+	}
+
+	for i < len(p.input) && isURLChar(p.input[i]) {
+		i++
+	}
+	if i >= len(p.input) {
+		// The URL might go on
+		p.peekedPastEnd = true
+	}
+	return i
+}
+
+// isURLSeparator reports whether the colon at offset i is followed by //, as
+// in https://example.com
+func (p *parser) isURLSeparator(i int) bool {
+	if i+3 > len(p.input) {
+		p.peekedPastEnd = true
+		return false
+	}
+	return p.input[i+1:i+3] == "//"
+}
+
+// findClosingQuote returns the offset of the closing quote of a string that
+// starts at the current position without an opening quote, like John Smith"
+// in {"name": John Smith"}, or -1 if there is none. The quote must end the
+// line or be followed by a colon for a key, or by the end of the value
+// otherwise, and come before any comment.
+func (p *parser) findClosingQuote(key bool) int {
+	for i := p.index; i < len(p.input); {
+		c := p.input[i]
+		if c == '\n' || c == ',' || c == '{' || c == '}' || c == '[' || c == ']' || (key && c == ':') || p.isCommentStart(i) {
+			return -1
+		}
+
+		r, size := utf8.DecodeRuneInString(p.input[i:])
+		if closingQuotes(r) != "" {
+			j := i + size
+			for j < len(p.input) && (p.input[j] == ' ' || p.input[j] == '\t' || p.input[j] == '\r') {
+				j++
+			}
+			if j >= len(p.input) {
+				p.peekedPastEnd = true
+				return i
+			}
+			if (key && p.input[j] == ':') || (!key && strings.IndexByte(",}]\n", p.input[j]) >= 0) {
+				return i
+			}
+		}
+		i += size
+	}
+
+	p.peekedPastEnd = true
+	return -1
+}
+
+// parseMissingOpeningQuote parses a string without an opening quote up to
+// its closing quote at end
+func (p *parser) parseMissingOpeningQuote(end int) error {
+	p.record(AddedOpeningQuote, p.index, p.index)
+	p.output.WriteByte('"')
+
+	for p.index < end {
+		if err := p.copyUnquotedRune(); err != nil {
+			return err
+		}
+	}
+
+	r, size := utf8.DecodeRuneInString(p.input[end:])
+	p.index += size
+	if r != '"' {
+		p.record(ReplacedQuotes, end, p.index)
+	}
+	p.output.WriteByte('"')
+	return nil
+}
+
+// copyUnquotedRune copies the character at the current position of a string
+// without quotes to the output, escaping quotes and backslashes
+func (p *parser) copyUnquotedRune() error {
+	switch c := p.input[p.index]; c {
+	case '"', '\\':
+		p.output.WriteByte('\\')
+		p.output.WriteByte(c)
+		p.index++
+		return nil
+	}
+	return p.copyRune()
+}
+
+func (p *parser) parseNumber() error {
+	start := p.index
+	outputStart := p.output.Len()
+
+	// lenient is set by repairs that need Options.LenientNumbers, and
+	// truncated when the end of input cut off the number
+	lenient, truncated := false, false
+
+	// Optional sign
+	if p.input[p.index] == '+' {
+		lenient = true
+		p.index++
+	} else if p.input[p.index] == '-' {
+		p.output.WriteByte('-')
+		p.index++
+	}
+
+	// Hexadecimal, octal and binary integers like 0x1F, 0o17 and 0b101
+	if base := p.peekRadix(); base != 0 {
+		if err := p.parseRadixInteger(start, base); err != nil {
+			return err
 		}
-		first = false
+		if p.isWordAfterNumber() {
+			return p.parseWordAfterNumber(start, outputStart)
+		}
+		p.record(RepairedNumber, start, p.index)
+		return nil
+	}
 
-		if err := p.parseValue(); err != nil {
-			return err
+	// Integer part
+	integer := p.scanDigits()
+	switch {
+	case integer != "":
+		digits := strings.ReplaceAll(integer, "_", "")
+		trimmed := strings.TrimLeft(digits, "0")
+		if trimmed == "" {
+			trimmed = "0"
 		}
+		lenient = lenient || len(trimmed) != len(integer)
+		p.output.WriteString(trimmed)
+	case p.index+1 < len(p.input) && p.input[p.index] == '.' && isRadixDigit(p.input[p.index+1], 10):
+		// Bare dot like .5
+		lenient = true
+		p.output.WriteByte('0')
+	case p.index >= len(p.input) || (p.index+1 == len(p.input) && p.input[p.index] == '.'):
+		// Only a sign or a dot before the end of input
+		p.index = len(p.input)
+		p.output.Truncate(outputStart)
+		return p.closeTruncated(AddedMissingValue, "null")
+	default:
+		return p.errorAt(start, InvalidNumber, "invalid number")
+	}
 
-		p.skipWhitespaceAndComments()
+	// Fractional part
+	if p.index < len(p.input) && p.input[p.index] == '.' {
+		p.index++
+		fraction := p.scanDigits()
+		switch {
+		case fraction != "":
+			digits := strings.ReplaceAll(fraction, "_", "")
+			lenient = lenient || len(digits) != len(fraction)
+			p.output.WriteByte('.')
+			p.output.WriteString(digits)
+		case p.index >= len(p.input):
+			truncated = true
+		case p.input[p.index] == '.':
+			return p.errorAt(start, InvalidNumber, "invalid number")
+		default:
+			// Missing digits like 1.
+			lenient = true
+			p.output.WriteString(".0")
+		}
+	}
 
-		// Check for comma or end
-		if p.index < len(p.input) && p.input[p.index] == ',' {
+	// Exponent part
+	if !truncated && p.index < len(p.input) && (p.input[p.index] == 'e' || p.input[p.index] == 'E') {
+		exponentStart := p.output.Len()
+		p.output.WriteByte(p.input[p.index])
+		p.index++
+		if p.index < len(p.input) && (p.input[p.index] == '+' || p.input[p.index] == '-') {
+			p.output.WriteByte(p.input[p.index])
 			p.index++
-			p.skipWhitespaceAndComments()
-			// Check for trailing comma or ellipsis
-			if p.index < len(p.input) {
-				if p.input[p.index] == ']' {
-					break
-				}
-				// Check for ellipsis after comma
-				if p.index+2 < len(p.input) && p.input[p.index:p.index+3] == "..." {
-					p.index += 3
-					p.skipWhitespaceAndComments()
-					// Check if more values follow
-					if p.index >= len(p.input) || p.input[p.index] == ']' {
-						break
-					}
-					// Skip comma after ellipsis if present
-					if p.input[p.index] == ',' {
-						p.index++
-						p.skipWhitespaceAndComments()
-					}
-				}
-			}
+		}
+		exponent := p.scanDigits()
+		switch {
+		case exponent != "":
+			digits := strings.ReplaceAll(exponent, "_", "")
+			lenient = lenient || len(digits) != len(exponent)
+			p.output.WriteString(digits)
+		case p.index >= len(p.input):
+			truncated = true
+			p.output.Truncate(exponentStart)
+		default:
+			// Missing digits like 1e
+			lenient = true
+			p.output.WriteByte('0')
 		}
 	}
 
-	if p.index >= len(p.input) {
-		// Truncated - close the array
-		p.output.WriteByte(']')
-		return nil
+	if p.isWordAfterNumber() {
+		return p.parseWordAfterNumber(start, outputStart)
+	}
+	if lenient && !p.opts.LenientNumbers {
+		return p.errorAt(start, DisabledRepair, "invalid number")
+	}
+	if truncated {
+		// Keep the valid prefix of a number cut off by the end of input
+		if !p.opts.Truncation {
+			return p.errorf(DisabledRepair, "unexpected end of input")
+		}
+		p.record(RepairedNumber, start, p.index)
+	} else if lenient {
+		p.record(RepairedNumber, start, p.index)
 	}
-
-	p.output.WriteByte(']')
-	p.index++ // skip ']'
 	return nil
 }
 
-func (p *parser) parseKey() error {
-	if p.index >= len(p.input) {
-		return fmt.Errorf("unexpected end of input while parsing key")
+// isWordAfterNumber reports whether the number before the current position
+// is followed by text that makes it part of a word, like in 1.2.3, 2024-01-01
+// or 123abc
+func (p *parser) isWordAfterNumber() bool {
+	if !p.opts.UnquotedStrings || p.index >= len(p.input) {
+		return false
+	}
+	switch r, _ := p.peekRune(); r {
+	case '.', '_', '-', '+', ':':
+		return true
+	default:
+		return unicode.IsLetter(r)
 	}
+}
 
-	char := p.input[p.index]
+// parseWordAfterNumber parses a number that started at start again as an
+// unquoted string, because it is part of a word
+func (p *parser) parseWordAfterNumber(start, outputStart int) error {
+	p.rollback(start, outputStart, len(p.actions))
+	return p.parseUnquotedString()
+}
 
-	if char == '"' {
-		return p.parseString()
-	} else if char == '\'' {
-		return p.parseSingleQuotedString()
-	} else {
-		// Unquoted key - need to add quotes
-		return p.parseUnquotedKey()
+// peekRadix returns the base of a hexadecimal, octal or binary integer
+// prefix like 0x at the current position, or 0 if there is none
+func (p *parser) peekRadix() int {
+	if p.index+1 >= len(p.input) || p.input[p.index] != '0' {
+		return 0
 	}
+	switch p.input[p.index+1] {
+	case 'x', 'X':
+		return 16
+	case 'o', 'O':
+		return 8
+	case 'b', 'B':
+		return 2
+	}
+	return 0
 }
 
-func (p *parser) parseString() error {
-	p.output.WriteByte('"')
-	p.index++ // skip opening quote
+// parseRadixInteger converts a hexadecimal, octal or binary integer to a
+// decimal one. Its sign, if any, was already parsed, and the repair is
+// recorded by the caller.
+func (p *parser) parseRadixInteger(start, base int) error {
+	if !p.opts.LenientNumbers {
+		return p.errorAt(start, DisabledRepair, "invalid number")
+	}
+	p.index += 2 // skip prefix like 0x
 
+	digitsStart := p.index
 	for p.index < len(p.input) {
-		char := p.input[p.index]
-
-		if char == '"' {
+		c := p.input[p.index]
+		if c == '_' && p.index > digitsStart && p.index+1 < len(p.input) && isRadixDigit(p.input[p.index+1], base) {
 			p.index++
-
-			// Check for concatenation with +
-			savedIndex := p.index
-			p.skipWhitespaceAndComments()
-			if p.index < len(p.input) && p.input[p.index] == '+' {
-				p.index++
-				p.skipWhitespaceAndComments()
-				if p.index < len(p.input) && (p.input[p.index] == '"' || p.input[p.index] == '\'') {
-					// Continue concatenating - don't close the quote yet; skip opening quote of next string
-					p.index++ // skip opening quote (single or double)
-					continue
-				}
-			}
-			// No concatenation, restore index and close quote
-			p.index = savedIndex
-			p.output.WriteByte('"')
-			return nil
-		} else if char == '\\' {
-			if p.index+1 < len(p.input) {
-				p.output.WriteByte('\\')
-				p.index++
-				p.output.WriteByte(p.input[p.index])
-				p.index++
-			} else {
-				p.output.WriteByte('\\')
-				p.index++
-			}
-		} else {
-			p.output.WriteByte(char)
+		} else if isRadixDigit(c, base) {
 			p.index++
+		} else {
+			break
 		}
 	}
 
-	// Unterminated string - close it
-	p.output.WriteByte('"')
+	digits := strings.ReplaceAll(p.input[digitsStart:p.index], "_", "")
+	if digits == "" {
+		if p.index < len(p.input) {
+			return p.errorAt(start, InvalidNumber, "invalid number")
+		}
+		// Cut off by the end of input - keep the valid prefix 0
+		if !p.opts.Truncation {
+			return p.errorf(DisabledRepair, "unexpected end of input")
+		}
+		digits = "0"
+	}
+
+	n, _ := new(big.Int).SetString(digits, base)
+	p.output.WriteString(n.String())
 	return nil
 }
 
-func (p *parser) parseSingleQuotedString() error {
-	p.output.WriteByte('"') // Convert to double quote
-	p.index++               // skip opening single quote
-
+// scanDigits skips decimal digits at the current position, together with
+// underscores between them, and returns them
+func (p *parser) scanDigits() string {
+	start := p.index
 	for p.index < len(p.input) {
-		char := p.input[p.index]
-
-		if char == '\'' {
+		c := p.input[p.index]
+		if c == '_' && p.index > start && p.index+1 < len(p.input) && isRadixDigit(p.input[p.index+1], 10) {
 			p.index++
-
-			// Check for concatenation
-			savedIndex := p.index
-			p.skipWhitespaceAndComments()
-			if p.index < len(p.input) && p.input[p.index] == '+' {
-				p.index++
-				p.skipWhitespaceAndComments()
-				if p.index < len(p.input) && (p.input[p.index] == '"' || p.input[p.index] == '\'') {
-					// Continue concatenating
-					p.index++
-					continue
-				}
-			}
-			// No concatenation, restore and close
-			p.index = savedIndex
-			p.output.WriteByte('"') // Convert to double quote
-			return nil
-		} else if char == '\\' {
-			if p.index+1 < len(p.input) {
-				nextChar := p.input[p.index+1]
-				if nextChar == '\'' {
-					// Escaped single quote - just output the quote
-					p.output.WriteByte('\'')
-					p.index += 2
-				} else {
-					p.output.WriteByte('\\')
-					p.index++
-					p.output.WriteByte(p.input[p.index])
-					p.index++
-				}
-			} else {
-				p.output.WriteByte('\\')
-				p.index++
-			}
-		} else if char == '"' {
-			// Double quote inside single-quoted string needs to be escaped
-			p.output.WriteString("\\\"")
+		} else if c >= '0' && c <= '9' {
 			p.index++
 		} else {
-			p.output.WriteByte(char)
-			p.index++
+			break
 		}
 	}
+	return p.input[start:p.index]
+}
 
-	// Unterminated string - close it
-	p.output.WriteByte('"')
-	return nil
+// isRadixDigit reports whether c is a digit in base 2, 8, 10 or 16
+func isRadixDigit(c byte, base int) bool {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c-'0') < base
+	case base == 16:
+		return c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+	}
+	return false
 }
 
-func (p *parser) parseUnquotedKey() error {
-	start := p.index
+// keywords are the words parsed as literals, with their JSON spelling
+var keywords = []struct {
+	word   string
+	json   string
+	python bool
+}{
+	{"true", "true", false},
+	{"false", "false", false},
+	{"null", "null", false},
+	{"True", "true", true},
+	{"False", "false", true},
+	{"None", "null", true},
+}
 
-	// Read until we hit a colon, whitespace, or comment
-	for p.index < len(p.input) {
-		char := p.input[p.index]
-		if char == ':' || unicode.IsSpace(rune(char)) || char == '/' {
-			break
+// parseKeyword parses true, false, null or a Python constant. A keyword cut
+// off by the end of input is completed, and any other word is parsed as an
+// unquoted string.
+func (p *parser) parseKeyword() error {
+	start := p.index
+	rest := p.input[start:]
+	for _, k := range keywords {
+		switch {
+		case strings.HasPrefix(rest, k.word):
+			if p.isWordAt(start + len(k.word)) {
+				continue
+			}
+			if k.python && !p.opts.PythonConstants {
+				return p.errorf(DisabledRepair, "unexpected Python constant %s", k.word)
+			}
+			p.index += len(k.word)
+			if k.python {
+				p.record(ConvertedPythonConstant, start, p.index)
+			}
+		case strings.HasPrefix(k.word, rest) && (!k.python || p.opts.PythonConstants):
+			// Truncated keyword, like tru
+			p.peekedPastEnd = true
+			p.index = len(p.input)
+			if !p.opts.Truncation {
+				return p.errorf(DisabledRepair, "unexpected end of input")
+			}
+			p.record(CompletedKeyword, start, p.index)
+		default:
+			continue
 		}
-		p.index++
+		p.output.WriteString(k.json)
+		return nil
 	}
 
-	key := p.input[start:p.index]
-	p.output.WriteByte('"')
-	p.output.WriteString(key)
-	p.output.WriteByte('"')
-
-	return nil
+	if !p.opts.UnquotedStrings {
+		return p.errorf(DisabledRepair, "expected true, false or null")
+	}
+	return p.parseUnquotedString()
 }
 
-func (p *parser) parseUnquotedString() error {
-	// This handles unquoted strings that should be quoted
-	// We quote them as strings
-	start := p.index
+// isWordAt reports whether a letter, digit or underscore is at i, so that a
+// keyword before it is only the start of a longer word
+func (p *parser) isWordAt(i int) bool {
+	if i >= len(p.input) {
+		p.peekedPastEnd = true
+		return false
+	}
+	if !utf8.FullRuneInString(p.input[i:]) {
+		// A multi-byte letter may be cut off by the end of input
+		p.peekedPastEnd = true
+	}
+	r, _ := utf8.DecodeRuneInString(p.input[i:])
+	return isIdentRune(r)
+}
 
-	for p.index < len(p.input) {
-		char := p.input[p.index]
-		if unicode.IsSpace(rune(char)) || char == ',' || char == '}' || char == ']' || char == ':' {
-			break
+func (p *parser) peekKeyword(keyword string) bool {
+	if p.index+len(keyword) > len(p.input) {
+		// Only text that can still become keyword depends on what follows
+		if strings.HasPrefix(keyword, p.input[p.index:]) {
+			p.peekedPastEnd = true
 		}
-		p.index++
+		return false
 	}
+	return p.input[p.index:p.index+len(keyword)] == keyword
+}
 
-	value := p.input[start:p.index]
-	p.output.WriteByte('"')
-	p.output.WriteString(value)
-	p.output.WriteByte('"')
+// peekMongoDBType returns the name of the MongoDB type at the current
+// position, or an empty string if there is none
+func (p *parser) peekMongoDBType() string {
+	for _, name := range mongoDBTypes {
+		if p.peekKeyword(name) {
+			return name
+		}
+	}
+	return ""
+}
 
+// closeTruncated writes the text needed to close a value that was cut off by
+// the end of input, or fails when truncation repair is disabled
+func (p *parser) closeTruncated(kind ActionKind, closing string) error {
+	if !p.opts.Truncation {
+		return p.errorf(DisabledRepair, "unexpected end of input")
+	}
+	p.record(kind, p.index, p.index)
+	p.output.WriteString(closing)
 	return nil
 }
 
-func (p *parser) parseNumber() error {
-	start := p.index
+// errorf returns a *RepairError of the given kind at the current position
+func (p *parser) errorf(kind ErrorKind, format string, args ...any) error {
+	return p.errorAt(p.index, kind, format, args...)
+}
 
-	// Optional minus
-	if p.index < len(p.input) && p.input[p.index] == '-' {
-		p.index++
-	}
+// errorAt returns a *RepairError of the given kind at offset
+func (p *parser) errorAt(offset int, kind ErrorKind, format string, args ...any) error {
+	return newRepairError(p.input, offset, kind, fmt.Sprintf(format, args...))
+}
 
-	// Integer part
-	if p.index >= len(p.input) {
-		return fmt.Errorf("invalid number at position %d", start)
-	}
+// unexpectedCharacter returns an error for the character at the current
+// position
+func (p *parser) unexpectedCharacter() error {
+	return p.errorf(UnexpectedCharacter, "unexpected character '%c'", p.currentRune())
+}
 
-	if p.input[p.index] == '0' {
-		p.index++
-	} else if p.input[p.index] >= '1' && p.input[p.index] <= '9' {
-		for p.index < len(p.input) && p.input[p.index] >= '0' && p.input[p.index] <= '9' {
-			p.index++
-		}
-	} else {
-		return fmt.Errorf("invalid number at position %d", start)
-	}
+// currentRune decodes the character at the current position
+func (p *parser) currentRune() rune {
+	r, _ := utf8.DecodeRuneInString(p.input[p.index:])
+	return r
+}
 
-	// Fractional part
-	if p.index < len(p.input) && p.input[p.index] == '.' {
-		p.index++
-		if p.index >= len(p.input) || p.input[p.index] < '0' || p.input[p.index] > '9' {
-			return fmt.Errorf("invalid number at position %d", start)
-		}
-		for p.index < len(p.input) && p.input[p.index] >= '0' && p.input[p.index] <= '9' {
-			p.index++
-		}
+// peekRune decodes the character at the current position. An invalid UTF-8
+// sequence decodes to utf8.RuneError with a size of 1.
+func (p *parser) peekRune() (rune, int) {
+	if c := p.input[p.index]; c < utf8.RuneSelf {
+		return rune(c), 1
 	}
+	if !utf8.FullRuneInString(p.input[p.index:]) {
+		// A multi-byte character may be cut off by the end of input
+		p.peekedPastEnd = true
+	}
+	return utf8.DecodeRuneInString(p.input[p.index:])
+}
 
-	// Exponent part
-	if p.index < len(p.input) && (p.input[p.index] == 'e' || p.input[p.index] == 'E') {
-		p.index++
-		if p.index < len(p.input) && (p.input[p.index] == '+' || p.input[p.index] == '-') {
-			p.index++
+// copyRune copies the character at the current position of a string to the
+// output, escaping control characters and replacing an invalid UTF-8 sequence
+// with U+FFFD
+func (p *parser) copyRune() error {
+	r, size := p.peekRune()
+	if r < ' ' {
+		if !p.opts.ControlCharacters {
+			return p.errorf(DisabledRepair, "unescaped control character in string")
 		}
-		if p.index >= len(p.input) || p.input[p.index] < '0' || p.input[p.index] > '9' {
-			return fmt.Errorf("invalid number at position %d", start)
+		p.record(EscapedControlCharacter, p.index, p.index+1)
+		switch r {
+		case '\b':
+			p.output.WriteString(`\b`)
+		case '\f':
+			p.output.WriteString(`\f`)
+		case '\n':
+			p.output.WriteString(`\n`)
+		case '\r':
+			p.output.WriteString(`\r`)
+		case '\t':
+			p.output.WriteString(`\t`)
+		default:
+			p.output.WriteString(`\u00`)
+			p.output.WriteByte(hexDigits[r>>4])
+			p.output.WriteByte(hexDigits[r&0xf])
 		}
-		for p.index < len(p.input) && p.input[p.index] >= '0' && p.input[p.index] <= '9' {
-			p.index++
+	} else if r == utf8.RuneError && size == 1 {
+		if !p.opts.InvalidUTF8 {
+			return p.errorf(DisabledRepair, "invalid UTF-8 sequence")
 		}
+		p.record(ReplacedInvalidUTF8, p.index, p.index+1)
+		p.output.WriteRune(utf8.RuneError)
+	} else {
+		p.output.WriteString(p.input[p.index : p.index+size])
 	}
-
-	p.output.WriteString(p.input[start:p.index])
+	p.index += size
 	return nil
 }
 
-func (p *parser) parseKeyword(keyword string) error {
-	if !p.matchKeyword(keyword) {
-		return fmt.Errorf("expected '%s' at position %d", keyword, p.index)
+// isIdentRune reports whether r can be part of a function name
+func isIdentRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// record adds a repair to the report
+func (p *parser) record(kind ActionKind, start, end int) {
+	if p.report {
+		p.actions = append(p.actions, Action{Kind: kind, Start: start, End: end})
 	}
-	p.output.WriteString(keyword)
-	return nil
 }
 
-func (p *parser) matchKeyword(keyword string) bool {
-	if p.index+len(keyword) > len(p.input) {
-		return false
+// peekNonFinite returns the non-finite literal that makes up the value at
+// the current position, or an empty string if there is none
+func (p *parser) peekNonFinite() string {
+	for _, literal := range nonFiniteLiterals {
+		if strings.HasPrefix(p.input[p.index:], literal) && p.isValueEnd(p.index+len(literal)) {
+			return literal
+		}
+	}
+	return ""
+}
+
+// isValueEnd reports whether a value can end at offset i, because only
+// whitespace separates it from a delimiter, a comment or the end of input
+func (p *parser) isValueEnd(i int) bool {
+	for i < len(p.input) && (p.input[i] == ' ' || p.input[i] == '\t' || p.input[i] == '\r') {
+		i++
 	}
-	if p.input[p.index:p.index+len(keyword)] == keyword {
-		p.index += len(keyword)
+	if i >= len(p.input) {
+		p.peekedPastEnd = true
 		return true
 	}
-	return false
+	switch p.input[i] {
+	case ',', '}', ']', ')', '\n':
+		return true
+	}
+	return p.isCommentStart(i)
 }
 
-func (p *parser) peekKeyword(keyword string) bool {
-	if p.index+len(keyword) > len(p.input) {
-		return false
+// parseNonFinite repairs a non-finite literal as set by Options.NonFinite
+func (p *parser) parseNonFinite(literal string) error {
+	switch p.opts.NonFinite {
+	case NonFiniteNull:
+		p.output.WriteString("null")
+	case NonFiniteString:
+		p.output.WriteByte('"')
+		p.output.WriteString(literal)
+		p.output.WriteByte('"')
+	default:
+		return p.errorf(DisabledRepair, "unexpected %s", literal)
 	}
-	return p.input[p.index:p.index+len(keyword)] == keyword
+	p.record(ConvertedNonFinite, p.index, p.index+len(literal))
+	p.index += len(literal)
+	return nil
 }
 
-func (p *parser) parseMongoDBType() error {
-	// Function name already consumed by caller
+func (p *parser) parseMongoDBType(name string) error {
+	start := p.index
+	p.index += len(name)
 	p.skipWhitespaceAndComments()
 
 	if p.index >= len(p.input) || p.input[p.index] != '(' {
-		return fmt.Errorf("invalid MongoDB type - expected '(' at position %d", p.index)
+		return p.errorf(InvalidMongoDBType, "invalid MongoDB type - expected '('")
 	}
 
+	p.push('(', start)
 	p.index++ // skip '('
 	p.skipWhitespaceAndComments()
 
@@ -522,16 +2170,24 @@ func (p *parser) parseMongoDBType() error {
 		return err
 	}
 
+	return p.closeMongoDBType(start)
+}
+
+// closeMongoDBType skips the closing parenthesis of a MongoDB type whose
+// name starts at start
+func (p *parser) closeMongoDBType(start int) error {
 	p.skipWhitespaceAndComments()
 
 	if p.index >= len(p.input) {
-		return fmt.Errorf("invalid MongoDB type - expected ')' but reached end of input")
+		return p.errorf(InvalidMongoDBType, "invalid MongoDB type - expected ')' but reached end of input")
 	}
 	if p.input[p.index] != ')' {
-		return fmt.Errorf("invalid MongoDB type - expected ')' but found '%c' at position %d", p.input[p.index], p.index)
+		return p.errorf(InvalidMongoDBType, "invalid MongoDB type - expected ')' but found '%c'", p.currentRune())
 	}
 
 	p.index++ // skip ')'
+	p.pop()
+	p.record(StrippedMongoType, start, p.index)
 
 	return nil
 }
@@ -540,26 +2196,36 @@ func (p *parser) skipWhitespaceAndComments() {
 	for p.index < len(p.input) {
 		char := p.input[p.index]
 
-		if unicode.IsSpace(rune(char)) {
+		if char == ' ' || char == '\t' || char == '\n' || char == '\r' {
 			p.index++
-		} else if char == '/' && p.index+1 < len(p.input) {
+		} else if r, size := p.peekRune(); p.opts.InvalidWhitespace && unicode.IsSpace(r) {
+			p.record(RemovedWhitespace, p.index, p.index+size)
+			p.index += size
+		} else if p.opts.Comments && char == '/' && p.index+1 >= len(p.input) {
+			// Might be the start of a comment cut off by the end of input
+			p.peekedPastEnd = true
+			break
+		} else if p.opts.Comments && char == '/' {
 			nextChar := p.input[p.index+1]
 			if nextChar == '/' {
 				// Single-line comment
+				start := p.index
 				p.index += 2
 				for p.index < len(p.input) && p.input[p.index] != '\n' {
 					p.index++
 				}
+				p.record(RemovedComment, start, p.index)
 			} else if nextChar == '*' {
 				// Multi-line comment
-				p.index += 2
-				for p.index+1 < len(p.input) {
-					if p.input[p.index] == '*' && p.input[p.index+1] == '/' {
-						p.index += 2
-						break
-					}
-					p.index++
+				start := p.index
+				end := strings.Index(p.input[p.index+2:], "*/")
+				if end < 0 {
+					// Unterminated comment runs to the end of input
+					p.index = len(p.input)
+				} else {
+					p.index += 2 + end + 2
 				}
+				p.record(RemovedComment, start, p.index)
 			} else {
 				break
 			}
@@ -569,68 +2235,97 @@ func (p *parser) skipWhitespaceAndComments() {
 	}
 }
 
+// checkWhitespace returns an error for whitespace or a comment that JSON does
+// not allow at the current position, which skipWhitespaceAndComments leaves
+// in place when InvalidWhitespace or Comments is disabled
+func (p *parser) checkWhitespace() error {
+	rest := p.input[p.index:]
+	if !p.opts.Comments && (strings.HasPrefix(rest, "//") || strings.HasPrefix(rest, "/*")) {
+		return p.errorf(DisabledRepair, "unexpected comment")
+	}
+	if p.opts.InvalidWhitespace || p.input[p.index] < utf8.RuneSelf {
+		return nil
+	}
+	if r, _ := p.peekRune(); unicode.IsSpace(r) {
+		return p.errorf(DisabledRepair, "unexpected whitespace")
+	}
+	return nil
+}
+
 func (p *parser) peekFunc() bool {
 	// Check if this looks like a function call (JSONP wrapper)
 	saved := p.index
 
 	// Skip identifier
-	if p.index >= len(p.input) || !unicode.IsLetter(rune(p.input[p.index])) {
+	if p.index >= len(p.input) {
+		return false
+	}
+	if r, _ := p.peekRune(); !unicode.IsLetter(r) {
 		return false
 	}
 
-	for p.index < len(p.input) && (unicode.IsLetter(rune(p.input[p.index])) || unicode.IsDigit(rune(p.input[p.index])) || p.input[p.index] == '_') {
-		p.index++
+	for p.index < len(p.input) {
+		r, size := p.peekRune()
+		if !isIdentRune(r) {
+			break
+		}
+		p.index += size
 	}
 
 	// Skip whitespace
-	for p.index < len(p.input) && unicode.IsSpace(rune(p.input[p.index])) {
-		p.index++
+	for p.index < len(p.input) {
+		r, size := p.peekRune()
+		if !unicode.IsSpace(r) {
+			break
+		}
+		p.index += size
 	}
 
 	// Check for opening parenthesis
+	if p.index >= len(p.input) {
+		p.peekedPastEnd = true
+	}
 	result := p.index < len(p.input) && p.input[p.index] == '('
 	p.index = saved
 	return result
 }
 
 func (p *parser) peekCodeFence() bool {
-	if p.index+2 < len(p.input) && p.input[p.index:p.index+3] == "```" {
-		return true
-	}
-	return false
+	return p.peekKeyword("```")
 }
 
-func (p *parser) parseJSONPWrapper() (string, error) {
+// openJSONPWrapper skips the function name and opening parenthesis of a
+// JSONP wrapper
+func (p *parser) openJSONPWrapper() error {
+	start := p.index
+
 	// Skip function name
-	for p.index < len(p.input) && (unicode.IsLetter(rune(p.input[p.index])) || unicode.IsDigit(rune(p.input[p.index])) || p.input[p.index] == '_') {
-		p.index++
+	for p.index < len(p.input) {
+		r, size := p.peekRune()
+		if !isIdentRune(r) {
+			break
+		}
+		p.index += size
 	}
 
 	p.skipWhitespaceAndComments()
 
 	if p.index >= len(p.input) || p.input[p.index] != '(' {
-		return "", fmt.Errorf("expected '(' for JSONP wrapper")
+		return p.errorf(InvalidJSONP, "expected '(' for JSONP wrapper")
 	}
 	p.index++ // skip '('
+	p.record(StrippedJSONP, start, p.index)
+	p.wrapper = '('
 
 	p.skipWhitespaceAndComments()
-
-	// Now parse the actual JSON value
-	if err := p.parseValue(); err != nil {
-		return "", err
-	}
-
-	p.skipWhitespaceAndComments()
-
-	// Skip closing parenthesis if present
-	if p.index < len(p.input) && p.input[p.index] == ')' {
-		p.index++
-	}
-
-	return p.output.String(), nil
+	return nil
 }
 
-func (p *parser) parseCodeFence() (string, error) {
+// openCodeFence skips the opening ``` of a code fence and its language
+// identifier
+func (p *parser) openCodeFence() {
+	start := p.index
+
 	// Skip opening ```
 	p.index += 3
 
@@ -640,8 +2335,8 @@ func (p *parser) parseCodeFence() (string, error) {
 	for p.index < len(p.input) && p.input[p.index] != '\n' {
 		ch := p.input[p.index]
 		// Check for characters that definitely start JSON values
-		if ch == '{' || ch == '[' || ch == '"' || 
-		   (ch >= '0' && ch <= '9') || ch == '-' {
+		if ch == '{' || ch == '[' || ch == '"' ||
+			(ch >= '0' && ch <= '9') || ch == '-' {
 			break
 		}
 		// For single quotes, true, false, null - check if followed by valid JSON context
@@ -658,20 +2353,8 @@ func (p *parser) parseCodeFence() (string, error) {
 	if p.index < len(p.input) && p.input[p.index] == '\n' {
 		p.index++ // skip newline
 	}
+	p.record(StrippedCodeFence, start, p.index)
+	p.wrapper = '`'
 
 	p.skipWhitespaceAndComments()
-
-	// Parse the JSON content
-	if err := p.parseValue(); err != nil {
-		return "", err
-	}
-
-	p.skipWhitespaceAndComments()
-
-	// Skip closing ```
-	if p.index+2 < len(p.input) && p.input[p.index:p.index+3] == "```" {
-		p.index += 3
-	}
-
-	return p.output.String(), nil
 }
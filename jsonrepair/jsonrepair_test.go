@@ -2,6 +2,7 @@ package jsonrepair
 
 import (
 	"encoding/json"
+	"errors"
 	"testing"
 )
 
@@ -142,6 +143,11 @@ func TestRepairTruncatedJSON(t *testing.T) {
 			input:    `{"a": {"b": 1`,
 			expected: `{"a": {"b": 1}}`,
 		},
+		{
+			name:     "truncated after key",
+			input:    `{"a": 1, "b"`,
+			expected: `{"a": 1, "b": null}`,
+		},
 		{
 			name:     "truncated after colon",
 			input:    `{"a": 1, "b":`,
@@ -163,6 +169,68 @@ func TestRepairTruncatedJSON(t *testing.T) {
 	}
 }
 
+func TestRepairTruncatedKeywords(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"true", `{"ok": tru`, `{"ok":true}`},
+		{"false", `[fa`, `[false]`},
+		{"null", `[nul`, `[null]`},
+		{"single letter", `{"a": n`, `{"a":null}`},
+		{"Python constant", `[Tr`, `[true]`},
+		{"words starting like keywords", `{a: test, b: now, c: falsy}`, `{"a":"test","b":"now","c":"falsy"}`},
+		{"keywords followed by letters", `[nullable, trueish]`, `["nullable","trueish"]`},
+		{"partial keyword before the end of input", `[nul, tru]`, `["nul","tru"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Repair(tt.input)
+			if err != nil {
+				t.Fatalf("Repair() error = %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Repair() = %s, expected %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRepairMismatchedBrackets(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"array closed by a brace", `{"a": [1, 2}`, `{"a":[1,2]}`},
+		{"object closed by a bracket", `[{"a": 1]`, `[{"a":1}]`},
+		{"several containers closed at once", `{"a": [{"b": [1}`, `{"a":[{"b":[1]}]}`},
+		{"extra bracket after the root", `[1, 2]]`, `[1,2]`},
+		{"extra brace after the root", `{"a": 1}}`, `{"a":1}`},
+		{"unmatched bracket inside an object", `{"a": 1] , "b": 2}`, `{"a":1,"b":2}`},
+		{"unmatched brace inside an array", `[1 } 2]`, `[1,2]`},
+		{"unmatched brace at the end of input", `[1, 2}`, `[1,2]`},
+		{"trailing comma before a mismatched bracket", `[{"a": 1,]`, `[{"a":1}]`},
+		{"inside a JSONP wrapper", `callback({"a": [1}})`, `{"a":[1]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Repair(tt.input)
+			if err != nil {
+				t.Fatalf("Repair() error = %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Repair() = %s, expected %s", result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestRepairStringConcatenation(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -390,6 +458,44 @@ func TestRepairNumbers(t *testing.T) {
 	}
 }
 
+func TestRepairLenientNumbers(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"leading plus", `[+1, +1.5]`, `[1,1.5]`},
+		{"bare dot", `[.5, -.5]`, `[0.5,-0.5]`},
+		{"missing fraction digits", `[1., 1.e5]`, `[1.0,1.0e5]`},
+		{"missing exponent digits", `[1e, 1e+]`, `[1e0,1e+0]`},
+		{"hexadecimal", `[0x1F, -0X1f]`, `[31,-31]`},
+		{"octal", `[0o17]`, `[15]`},
+		{"binary", `[0b101]`, `[5]`},
+		{"large hexadecimal", `[0xFFFFFFFFFFFFFFFFFF]`, `[4722366482869645213695]`},
+		{"underscores", `[1_000_000, 0.000_1, 0xFF_FF]`, `[1000000,0.0001,65535]`},
+		{"leading zeros", `[007, -007, 00.5, 0]`, `[7,-7,0.5,0]`},
+		{"fraction cut off by the end of input", `[12.`, `[12]`},
+		{"exponent cut off by the end of input", `[3e`, `[3]`},
+		{"signed exponent cut off by the end of input", `{"a": 3e-`, `{"a":3}`},
+		{"prefix cut off by the end of input", `[0x`, `[0]`},
+		{"sign cut off by the end of input", `{"a": -`, `{"a":null}`},
+		{"words starting with digits", `[1.2.3, 2024-01-01, 123abc]`, `["1.2.3","2024-01-01","123abc"]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Repair(tt.input)
+			if err != nil {
+				t.Fatalf("Repair() error = %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Repair() = %s, expected %s", result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestRepairComplexCases(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -427,6 +533,859 @@ func TestRepairComplexCases(t *testing.T) {
 	}
 }
 
+func TestRepairMissingClosingQuotes(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "comma followed by the next key",
+			input:    `{"a": "hello, "b": 2}`,
+			expected: `{"a":"hello","b":2}`,
+		},
+		{
+			name:     "comma followed by the next element",
+			input:    `["a", "b, "c"]`,
+			expected: `["a","b","c"]`,
+		},
+		{
+			name:     "newline followed by the next key",
+			input:    "{\n  \"name\": \"John\n  \"age\": 30\n}",
+			expected: `{"name":"John","age":30}`,
+		},
+		{
+			name:     "space followed by the next key",
+			input:    `{"a": "hello "b": 2}`,
+			expected: `{"a":"hello","b":2}`,
+		},
+		{
+			name:     "closing bracket at the end of input",
+			input:    `["hello]`,
+			expected: `["hello"]`,
+		},
+		{
+			name:     "closing brace at the end of input",
+			input:    `{"a": "hello}`,
+			expected: `{"a":"hello"}`,
+		},
+		{
+			name:     "comment after the string",
+			input:    "{\"a\": \"hello // comment\n}",
+			expected: `{"a":"hello"}`,
+		},
+		{
+			name:     "URL cut off by a closing brace",
+			input:    `{"url": "https://example.com/a?b=1}`,
+			expected: `{"url":"https://example.com/a?b=1"}`,
+		},
+		{
+			name:     "quotes inside a string are escaped",
+			input:    `{"a": "He said "hi" to me"}`,
+			expected: `{"a":"He said \"hi\" to me"}`,
+		},
+		{
+			name:     "apostrophe inside a single quoted string",
+			input:    `{'text': 'it's fine', 'b': 1}`,
+			expected: `{"text":"it's fine","b":1}`,
+		},
+		{
+			name:     "string followed by a number",
+			input:    `["a" 2]`,
+			expected: `["a",2]`,
+		},
+		{
+			name:     "concatenated string with a missing closing quote",
+			input:    `{"a": "x" + "y, "b": 1}`,
+			expected: `{"a":"xy","b":1}`,
+		},
+		{
+			name:     "truncated string without a delimiter",
+			input:    `["a/b`,
+			expected: `["a/b"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Repair(tt.input)
+			if err != nil {
+				t.Fatalf("Repair() error = %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Repair() = %s, expected %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRepairUnquotedStrings(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "values with spaces",
+			input:    `{status: in progress, owner: Jane Doe}`,
+			expected: `{"status":"in progress","owner":"Jane Doe"}`,
+		},
+		{
+			name:     "values with punctuation",
+			input:    `[John's book, a.b-c=d?, 1]`,
+			expected: `["John's book","a.b-c=d?",1]`,
+		},
+		{
+			name:     "values end at a newline",
+			input:    "{a: hello world\nb: 2}",
+			expected: `{"a":"hello world","b":2}`,
+		},
+		{
+			name:     "whitespace around values is trimmed",
+			input:    "{a:   x\ty   , b: z  }",
+			expected: `{"a":"x\ty","b":"z"}`,
+		},
+		{
+			name:     "comment after a value",
+			input:    "{a: hello world // note\n, b: 2}",
+			expected: `{"a":"hello world","b":2}`,
+		},
+		{
+			name:     "colons inside values",
+			input:    `{url: https://example.com/a, time: at 10:30}`,
+			expected: `{"url":"https://example.com/a","time":"at 10:30"}`,
+		},
+		{
+			name:     "quotes and backslashes are escaped",
+			input:    `{path: C:\temp, say: a "b" c}`,
+			expected: `{"path":"C:\\temp","say":"a \"b\" c"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Repair(tt.input)
+			if err != nil {
+				t.Fatalf("Repair() error = %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Repair() = %s, expected %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRepairNonFinite(t *testing.T) {
+	input := `{"a": NaN, "b": Infinity, "c": -Infinity, "d": undefined, "e": [nan, inf, -inf], "f": inf loop}`
+
+	tests := []struct {
+		name     string
+		policy   NonFinitePolicy
+		expected string
+	}{
+		{
+			name:     "null",
+			policy:   NonFiniteNull,
+			expected: `{"a":null,"b":null,"c":null,"d":null,"e":[null,null,null],"f":"inf loop"}`,
+		},
+		{
+			name:     "string",
+			policy:   NonFiniteString,
+			expected: `{"a":"NaN","b":"Infinity","c":"-Infinity","d":"undefined","e":["nan","inf","-inf"],"f":"inf loop"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.NonFinite = tt.policy
+			result, err := RepairWithOptions(input, opts)
+			if err != nil {
+				t.Fatalf("RepairWithOptions() error = %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("RepairWithOptions() = %s, expected %s", result, tt.expected)
+			}
+		})
+	}
+
+	opts := DefaultOptions()
+	opts.NonFinite = NonFiniteError
+	if _, err := RepairWithOptions(input, opts); err == nil {
+		t.Errorf("RepairWithOptions() expected an error")
+	}
+}
+
+func TestRepairTrailingContent(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		policy   TrailingContentPolicy
+		expected string
+	}{
+		{"discard", `{"a": 1} oops {"b": 2}`, TrailingContentDiscard, `{"a":1}`},
+		{"discard after a code fence", "```json\n{\"a\": 1}\n```\nLet me know", TrailingContentDiscard, `{"a":1}`},
+		{"array", `{"a": 1} {"b": 2}`, TrailingContentArray, `[{"a":1},{"b":2}]`},
+		{"array without whitespace", `[1][2]`, TrailingContentArray, `[[1],[2]]`},
+		{"array of comma-separated values", `{"a": 1}, {"b": 2},`, TrailingContentArray, `[{"a":1},{"b":2}]`},
+		{"array of truncated values", `{"a": 1} {"b": [2`, TrailingContentArray, `[{"a":1},{"b":[2]}]`},
+		{"array inside a code fence", "```json\n{\"a\": 1}\n{\"b\": 2}\n```\nbye", TrailingContentArray, `[{"a":1},{"b":2},"bye"]`},
+		{"array after a code fence", "```json\n{\"a\": 1}\n```\n{\"b\": 2}", TrailingContentArray, `[{"a":1},{"b":2}]`},
+		{"array after a JSONP wrapper", `callback({"a": 1}); {"b": 2}`, TrailingContentArray, `[{"a":1},{"b":2}]`},
+		{"discard after a JSONP wrapper", `callback({"a": 1}); {"b": 2}`, TrailingContentDiscard, `{"a":1}`},
+		{"error with a JSONP semicolon", `callback({"a": 1});`, TrailingContentError, `{"a":1}`},
+		{"array of a single value", `{"a": 1} // done`, TrailingContentArray, `{"a":1}`},
+		{"error without trailing content", "{\"a\": 1}\n", TrailingContentError, `{"a":1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.TrailingContent = tt.policy
+			result, err := RepairWithOptions(tt.input, opts)
+			if err != nil {
+				t.Fatalf("RepairWithOptions() error = %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("RepairWithOptions() = %s, expected %s", result, tt.expected)
+			}
+		})
+	}
+
+	opts := DefaultOptions()
+	opts.TrailingContent = TrailingContentError
+	if _, err := RepairWithOptions(`{"a": 1} oops`, opts); err == nil {
+		t.Errorf("RepairWithOptions() expected an error")
+	}
+}
+
+func TestRepairSlashes(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "URL value",
+			input:    `{url: https://example.com/a?b=1#c}`,
+			expected: `{"url":"https://example.com/a?b=1#c"}`,
+		},
+		{
+			name:     "URL key",
+			input:    `{https://example.com/a: 1}`,
+			expected: `{"https://example.com/a":1}`,
+		},
+		{
+			name:     "URL with // in its path",
+			input:    `{url: https://example.com/a//b, s3: s3://bucket/x//y.tar.gz}`,
+			expected: `{"url":"https://example.com/a//b","s3":"s3://bucket/x//y.tar.gz"}`,
+		},
+		{
+			name:     "URL key with // in its path",
+			input:    `{https://example.com/a//b: 1}`,
+			expected: `{"https://example.com/a//b":1}`,
+		},
+		{
+			name:     "paths",
+			input:    `{a/b/c: 1, d: a/b/c, e: src/*.go}`,
+			expected: `{"a/b/c":1,"d":"a/b/c","e":"src/*.go"}`,
+		},
+		{
+			name:     "URL in an array",
+			input:    `[a/b, http://x.y/z]`,
+			expected: `["a/b","http://x.y/z"]`,
+		},
+		{
+			name:     "line comment after a value",
+			input:    "{a: b// c\n}",
+			expected: `{"a":"b"}`,
+		},
+		{
+			name:     "block comment after a value",
+			input:    `{a: b /* c */, d: 1}`,
+			expected: `{"a":"b","d":1}`,
+		},
+		{
+			name:     "line comment after a key",
+			input:    "{a// c\n: 1}",
+			expected: `{"a":1}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Repair(tt.input)
+			if err != nil {
+				t.Fatalf("Repair() error = %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Repair() = %s, expected %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRepairMissingOpeningQuotes(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "value",
+			input:    `{"name": John Smith"}`,
+			expected: `{"name":"John Smith"}`,
+		},
+		{
+			name:     "key",
+			input:    `{name": 1}`,
+			expected: `{"name":1}`,
+		},
+		{
+			name:     "keys with spaces",
+			input:    `{first name": 1, last name": "x"}`,
+			expected: `{"first name":1,"last name":"x"}`,
+		},
+		{
+			name:     "quotes inside the value are escaped",
+			input:    `{"a": John "Jr" Smith", "b": 2}`,
+			expected: `{"a":"John \"Jr\" Smith","b":2}`,
+		},
+		{
+			name:     "typographic closing quote",
+			input:    `{"a": x”, "b": y’}`,
+			expected: `{"a":"x","b":"y"}`,
+		},
+		{
+			name:     "array element at the end of input",
+			input:    `[abc"`,
+			expected: `["abc"]`,
+		},
+		{
+			name:     "quote not at the end of the value",
+			input:    `{"a": b"c}`,
+			expected: `{"a":"b\"c"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Repair(tt.input)
+			if err != nil {
+				t.Fatalf("Repair() error = %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Repair() = %s, expected %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRepairEscapes(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "valid escapes are kept",
+			input:    `"a\"b\\c\/d\b\f\n\r\t\u00e9\uD83D\uDE00"`,
+			expected: `"a\"b\\c\/d\b\f\n\r\t\u00e9\uD83D\uDE00"`,
+		},
+		{
+			name:     "stray backslashes",
+			input:    `"C:\path\to\data"`,
+			expected: `"C:\\path\\to\\data"`,
+		},
+		{
+			name:     "letter escapes next to stray backslashes",
+			input:    `["C:\path\file\new", "D:\data\backup", "\t\n"]`,
+			expected: `["C:\\path\\file\\new","D:\\data\\backup","\t\n"]`,
+		},
+		{
+			name:     "letter escapes next to unknown escapes",
+			input:    `{"msg": "Line one\nLine two matches \d+", "tab": "\ttab then \. dot"}`,
+			expected: `{"msg":"Line one\nLine two matches \\d+","tab":"\ttab then \\. dot"}`,
+		},
+		{
+			name:     "unknown escape",
+			input:    `"\a\q"`,
+			expected: `"\\a\\q"`,
+		},
+		{
+			name:     "hexadecimal escape",
+			input:    `"\x41\x7E"`,
+			expected: `"\u0041\u007E"`,
+		},
+		{
+			name:     "incomplete hexadecimal escape",
+			input:    `"\xZ"`,
+			expected: `"\\xZ"`,
+		},
+		{
+			name:     "octal escapes",
+			input:    `"\101\0\7\400"`,
+			expected: `"\u0041\u0000\u0007\u00200"`,
+		},
+		{
+			name:     "incomplete unicode escape",
+			input:    `"\u12 and \uZZZZ"`,
+			expected: `"\\u12 and \\uZZZZ"`,
+		},
+		{
+			name:     "unicode escape cut off by the end of input",
+			input:    `["ab\u12`,
+			expected: `["ab"]`,
+		},
+		{
+			name:     "backslash cut off by the end of input",
+			input:    `["ab\`,
+			expected: `["ab"]`,
+		},
+		{
+			name:     "lone high surrogate",
+			input:    `"a\uD83Db"`,
+			expected: "\"a\uFFFDb\"",
+		},
+		{
+			name:     "lone low surrogate",
+			input:    `"a\uDE00\u0041"`,
+			expected: "\"a\uFFFD\\u0041\"",
+		},
+		{
+			name:     "high surrogate followed by another high surrogate",
+			input:    `"\uD83D\uD83D\uDE00"`,
+			expected: "\"\uFFFD\\uD83D\\uDE00\"",
+		},
+		{
+			name:     "escaped single quote",
+			input:    `{"a": "it\'s", 'b': 'it\'s'}`,
+			expected: `{"a":"it's","b":"it's"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Repair(tt.input)
+			if err != nil {
+				t.Fatalf("Repair() error = %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Repair() = %s, expected %s", result, tt.expected)
+			}
+			if !json.Valid([]byte(result)) {
+				t.Errorf("Repair() = %s, which is not valid JSON", result)
+			}
+		})
+	}
+}
+
+func TestRepairControlCharacters(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "newlines and tabs",
+			input:    "{\"text\": \"line 1\nline 2\tend\r\n\"}",
+			expected: `{"text":"line 1\nline 2\tend\r\n"}`,
+		},
+		{
+			name:     "other control characters",
+			input:    "[\"a\x00b\x01c\x1f\bd\f\"]",
+			expected: `["a\u0000b\u0001c\u001f\bd\f"]`,
+		},
+		{
+			name:     "single quoted string",
+			input:    "['multi\nline']",
+			expected: `["multi\nline"]`,
+		},
+		{
+			name:     "unquoted value",
+			input:    "[a\x01b]",
+			expected: `["a\u0001b"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Repair(tt.input)
+			if err != nil {
+				t.Fatalf("Repair() error = %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Repair() = %s, expected %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRepairMissingSeparators(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "missing colons",
+			input:    `{"a" 1, "b" "x"}`,
+			expected: `{"a":1,"b":"x"}`,
+		},
+		{
+			name:     "missing colon after unquoted key",
+			input:    `{a [1, 2]}`,
+			expected: `{"a":[1,2]}`,
+		},
+		{
+			name:     "missing commas between members",
+			input:    `{"a": 1 "b": 2}`,
+			expected: `{"a":1,"b":2}`,
+		},
+		{
+			name:     "missing commas across newlines",
+			input:    "{\n  \"a\": 1\n  \"b\": {\"c\": true}\n  \"d\": [1\n2\n3]\n}",
+			expected: `{"a":1,"b":{"c":true},"d":[1,2,3]}`,
+		},
+		{
+			name:     "missing commas between array elements",
+			input:    `[{"a": 1} {"b": 2} "x" 'y']`,
+			expected: `[{"a":1},{"b":2},"x","y"]`,
+		},
+		{
+			name:     "missing colon and comma together",
+			input:    `{"a" 1 "b" 2}`,
+			expected: `{"a":1,"b":2}`,
+		},
+		{
+			name:     "ellipsis without a comma",
+			input:    `[1, 2 ...]`,
+			expected: `[1,2]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Repair(tt.input)
+			if err != nil {
+				t.Fatalf("Repair() error = %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Repair() = %s, expected %s", result, tt.expected)
+			}
+		})
+	}
+
+	if _, err := Repair(`{"a", "b": 1}`); err == nil {
+		t.Errorf("Repair() expected an error for a key without a value")
+	}
+}
+
+func TestRepairSpecialQuotes(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "typographic double and single quotes",
+			input:    `{“name”: ‘John’}`,
+			expected: `{"name":"John"}`,
+		},
+		{
+			name:     "low-9 opening quotes",
+			input:    `[„Hallo“, ‚Welt‘]`,
+			expected: `["Hallo","Welt"]`,
+		},
+		{
+			name:     "backticks",
+			input:    "{`a`: `b`}",
+			expected: `{"a":"b"}`,
+		},
+		{
+			name:     "primes",
+			input:    `{″a″: ′b′}`,
+			expected: `{"a":"b"}`,
+		},
+		{
+			name:     "full-width quotes",
+			input:    `{＂key＂: ＇value＇}`,
+			expected: `{"key":"value"}`,
+		},
+		{
+			name:     "guillemets",
+			input:    `{«a»: ‹b›, "c": »d«, "e": »f»}`,
+			expected: `{"a":"b","c":"d","e":"f"}`,
+		},
+		{
+			name:     "mixed typographic and straight double quotes",
+			input:    `{“name": “John"}`,
+			expected: `{"name":"John"}`,
+		},
+		{
+			name:     "straight quotes only close themselves",
+			input:    `{"a": "it’s «ok»", 'b': 'say “hi”'}`,
+			expected: `{"a":"it’s «ok»","b":"say “hi”"}`,
+		},
+		{
+			name:     "guillemets only close with their counterpart",
+			input:    `[«a “b” ‘c’»]`,
+			expected: `["a “b” ‘c’"]`,
+		},
+		{
+			name:     "double quotes inside other quotes are escaped",
+			input:    `[‘say "hi"’]`,
+			expected: `["say \"hi\""]`,
+		},
+		{
+			name:     "escaped closing quote",
+			input:    `[‘it\’s’]`,
+			expected: `["it’s"]`,
+		},
+		{
+			name:     "concatenation across quote styles",
+			input:    `["a" + ‘b’ + “c”]`,
+			expected: `["abc"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Repair(tt.input)
+			if err != nil {
+				t.Fatalf("Repair() error = %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Repair() = %s, expected %s", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRepairUnicode(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "multi-byte unquoted keys",
+			input:    `{名前: 1, café: 2}`,
+			expected: `{"名前":1,"café":2}`,
+		},
+		{
+			name:     "multi-byte unquoted value",
+			input:    `{"a": Zürich}`,
+			expected: `{"a":"Zürich"}`,
+		},
+		{
+			name:     "key with a continuation byte that decodes to U+0085 as a single byte",
+			input:    `{ą: 1}`,
+			expected: `{"ą":1}`,
+		},
+		{
+			name:     "non-ASCII whitespace between tokens",
+			input:    "{\u00a0\"a\":\u30001,\u2003\"b\": 2}",
+			expected: `{"a":1,"b":2}`,
+		},
+		{
+			name:     "non-ASCII whitespace ends unquoted values",
+			input:    "[abc\u3000, def]",
+			expected: `["abc","def"]`,
+		},
+		{
+			name:     "multi-byte strings are copied unchanged",
+			input:    `{"emoji": "😀", 'jp': 'こんにちは'}`,
+			expected: `{"emoji":"😀","jp":"こんにちは"}`,
+		},
+		{
+			name:     "invalid UTF-8 in a string is replaced",
+			input:    "{\"a\": \"x\xffy\"}",
+			expected: "{\"a\":\"x\uFFFDy\"}",
+		},
+		{
+			name:     "invalid UTF-8 in an unquoted key is replaced",
+			input:    "{k\xc3: 1}",
+			expected: "{\"k\uFFFD\":1}",
+		},
+		{
+			name:     "truncated multi-byte character at the end of input",
+			input:    "[\"caf\xc3",
+			expected: "[\"caf\uFFFD\"]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Repair(tt.input)
+			if err != nil {
+				t.Fatalf("Repair() error = %v", err)
+			}
+
+			if result != tt.expected {
+				t.Errorf("Repair() = %q, expected %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRepairWithOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		disable  func(*Options)
+		expected string
+	}{
+		{
+			name:     "comments and trailing commas still repaired",
+			input:    `{"a": 1, /* comment */ "b": [1, 2,],}`,
+			disable:  func(o *Options) { o.MongoDBTypes = false; o.Ellipsis = false },
+			expected: `{"a": 1, "b": [1, 2]}`,
+		},
+		{
+			name:     "missing quotes disabled keeps the string up to the end of input",
+			input:    `["hello]`,
+			disable:  func(o *Options) { o.MissingQuotes = false },
+			expected: `["hello]"]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			tt.disable(&opts)
+			result, err := RepairWithOptions(tt.input, opts)
+			if err != nil {
+				t.Fatalf("RepairWithOptions() error = %v", err)
+			}
+
+			if !jsonEqual(result, tt.expected) {
+				t.Errorf("RepairWithOptions() = %v, expected %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRepairWithOptionsDisabled(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		disable func(*Options)
+	}{
+		{"comments", "{\"a\": 1 // comment\n}", func(o *Options) { o.Comments = false }},
+		{"comment after an unquoted value", "{a: b // comment\n}", func(o *Options) { o.Comments = false }},
+		{"comment before a key", `{"a": 1, /* c */ "b": 2}`, func(o *Options) { o.Comments = false }},
+		{"comment before the root value", "// c\n{\"a\": 1}", func(o *Options) { o.Comments = false }},
+		{"trailing comma in object", `{"a": 1,}`, func(o *Options) { o.TrailingCommas = false }},
+		{"trailing comma in array", `[1, 2,]`, func(o *Options) { o.TrailingCommas = false }},
+		{"single quotes", `{'a': 1}`, func(o *Options) { o.SingleQuotes = false }},
+		{"special quotes", `{“a”: 1}`, func(o *Options) { o.SpecialQuotes = false }},
+		{"unquoted key", `{a: 1}`, func(o *Options) { o.UnquotedStrings = false }},
+		{"missing colon", `{"a" 1}`, func(o *Options) { o.MissingSeparators = false }},
+		{"missing comma", `[1 2]`, func(o *Options) { o.MissingSeparators = false }},
+		{"mismatched bracket", `{"a": [1}`, func(o *Options) { o.MismatchedBrackets = false }},
+		{"extra closing bracket", `[1]]`, func(o *Options) {
+			o.MismatchedBrackets = false
+			o.TrailingContent = TrailingContentError
+		}},
+		{"trailing content", `{"a": 1} oops`, func(o *Options) { o.TrailingContent = TrailingContentError }},
+		{"truncated object", `{"a": 1`, func(o *Options) { o.Truncation = false }},
+		{"truncated string", `"abc`, func(o *Options) { o.Truncation = false }},
+		{"MongoDB type", `{"_id": ObjectId("507f1f77bcf86cd799439011")}`, func(o *Options) { o.MongoDBTypes = false }},
+		{"JSONP", `callback({"a": 1})`, func(o *Options) { o.JSONP = false }},
+		{"code fence", "```json\n{\"a\": 1}\n```", func(o *Options) { o.CodeFences = false }},
+		{"ellipsis", `[1, 2, ...]`, func(o *Options) { o.Ellipsis = false }},
+		{"ellipsis without a comma", `[1, 2 ...]`, func(o *Options) { o.Ellipsis = false }},
+		{"lenient number", `[0x1F]`, func(o *Options) { o.LenientNumbers = false }},
+		{"truncated number", `[1.`, func(o *Options) { o.Truncation = false }},
+		{"truncated keyword", `[tru`, func(o *Options) { o.Truncation = false }},
+		{"word starting like a keyword", `[test]`, func(o *Options) { o.UnquotedStrings = false }},
+		{"Python constant", `{"a": True}`, func(o *Options) { o.PythonConstants = false }},
+		{"string concatenation", `{"a": "x" + "y"}`, func(o *Options) { o.StringConcatenation = false }},
+		{"non-finite number", `[NaN]`, func(o *Options) { o.NonFinite = NonFiniteError }},
+		{"escapes", `["C:\path"]`, func(o *Options) { o.Escapes = false }},
+		{"control characters", "[\"a\nb\"]", func(o *Options) { o.ControlCharacters = false }},
+		{"invalid UTF-8", "[\"\xff\"]", func(o *Options) { o.InvalidUTF8 = false }},
+		{"invalid whitespace", "{\u00a0\"a\": 1}", func(o *Options) { o.InvalidWhitespace = false }},
+		{"invalid whitespace between values", "[1\u3000 2]", func(o *Options) { o.InvalidWhitespace = false }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Repair(tt.input); err != nil {
+				t.Fatalf("Repair() error = %v", err)
+			}
+
+			opts := DefaultOptions()
+			tt.disable(&opts)
+			result, err := RepairWithOptions(tt.input, opts)
+			var repairErr *RepairError
+			if !errors.As(err, &repairErr) {
+				t.Fatalf("RepairWithOptions() = %v, %v, expected a RepairError", result, err)
+			}
+			if repairErr.Kind != DisabledRepair {
+				t.Errorf("RepairWithOptions() error = %v, expected a DisabledRepair", err)
+			}
+		})
+	}
+}
+
+func TestRepairBytes(t *testing.T) {
+	result, err := RepairBytes([]byte(`{name: 'John', tags: [1, 2,]}`))
+	if err != nil {
+		t.Fatalf("RepairBytes() error = %v", err)
+	}
+	if !jsonEqual(string(result), `{"name": "John", "tags": [1, 2]}`) {
+		t.Errorf("RepairBytes() = %s", result)
+	}
+
+	if _, err := RepairBytes([]byte(`{"a": @}`)); err == nil {
+		t.Errorf("RepairBytes() expected an error")
+	}
+}
+
+func TestAppendRepair(t *testing.T) {
+	dst := []byte(`prefix:`)
+	result, err := AppendRepair(dst, []byte(`[1, 2, 3,]`))
+	if err != nil {
+		t.Fatalf("AppendRepair() error = %v", err)
+	}
+	if string(result) != `prefix:[1,2,3]` {
+		t.Errorf("AppendRepair() = %s, expected %s", result, `prefix:[1,2,3]`)
+	}
+
+	result, err = AppendRepair(dst, []byte(`[@]`))
+	if err == nil || string(result) != `prefix:` {
+		t.Errorf("AppendRepair() = %s, %v, expected dst and an error", result, err)
+	}
+}
+
+func TestAppendRepairAllocations(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector makes sync.Pool allocate")
+	}
+
+	src := []byte(`{name: 'John', tags: [1, 2, 3,], nested: {a: True, b: [{c: null}]}}`)
+	buf := make([]byte, 0, 256)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		buf, _ = AppendRepair(buf[:0], src)
+	})
+	if allocs > 0 {
+		t.Errorf("AppendRepair() allocated %v times per call, expected none", allocs)
+	}
+}
+
 // Helper function to compare JSON strings by parsing and comparing
 func jsonEqual(a, b string) bool {
 	var va, vb interface{}
@@ -0,0 +1,113 @@
+package jsonrepair
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// NDJSONOptions controls RepairNDJSON
+type NDJSONOptions struct {
+	// Options are the repairs applied to each line
+	Options
+	// InvalidLine controls what happens to a line that cannot be repaired
+	InvalidLine InvalidLinePolicy
+}
+
+// DefaultNDJSONOptions returns DefaultOptions for every line, with lines that
+// cannot be repaired skipped
+func DefaultNDJSONOptions() NDJSONOptions {
+	return NDJSONOptions{
+		Options:     DefaultOptions(),
+		InvalidLine: InvalidLineSkip,
+	}
+}
+
+// InvalidLinePolicy controls what RepairNDJSON does with a line that cannot
+// be repaired
+type InvalidLinePolicy int
+
+const (
+	// InvalidLineAbort stops at the line and returns its error
+	InvalidLineAbort InvalidLinePolicy = iota
+	// InvalidLineSkip leaves the line out of the output
+	InvalidLineSkip
+	// InvalidLineNull writes null in place of the line
+	InvalidLineNull
+)
+
+// LineError is the error of a line of newline-delimited JSON that cannot be
+// repaired
+type LineError struct {
+	// Line is the 1-based number of the line in the input
+	Line int
+	// Err is the error of the line, usually a *RepairError with positions
+	// relative to the line
+	Err error
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// RepairNDJSON repairs newline-delimited JSON (JSON Lines) read from r and
+// writes one repaired value per line to w. Each line is repaired on its own
+// and blank lines are dropped.
+//
+// A line that cannot be repaired is handled as set by opts.InvalidLine. When
+// such lines are skipped or replaced by null, every line is still written and
+// the returned error joins a *LineError for each of them.
+func RepairNDJSON(r io.Reader, w io.Writer, opts NDJSONOptions) error {
+	br := bufio.NewReader(r)
+	bw := bufio.NewWriter(w)
+
+	var lineErrs []error
+	var out []byte
+	for n := 1; ; n++ {
+		line, readErr := br.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return readErr
+		}
+
+		line = bytes.TrimRight(line, "\r\n")
+		if len(bytes.TrimSpace(line)) > 0 {
+			var err error
+			if out, err = appendRepair(out[:0], line, opts.Options); err != nil {
+				lineErr := &LineError{Line: n, Err: err}
+				switch opts.InvalidLine {
+				case InvalidLineSkip:
+					out = out[:0]
+				case InvalidLineNull:
+					out = append(out[:0], "null"...)
+				default:
+					if flushErr := bw.Flush(); flushErr != nil {
+						return flushErr
+					}
+					return lineErr
+				}
+				lineErrs = append(lineErrs, lineErr)
+			}
+			if len(out) > 0 {
+				out = append(out, '\n')
+				if _, err := bw.Write(out); err != nil {
+					return err
+				}
+			}
+		}
+
+		if readErr != nil {
+			break
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return errors.Join(lineErrs...)
+}
@@ -0,0 +1,73 @@
+package jsonrepair
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRepairNDJSON(t *testing.T) {
+	input := "{name: 'John', age: 30,}\r\n\n[1, 2\n{\"a\": @}\n{\"b\": tru\n"
+
+	tests := []struct {
+		name     string
+		policy   InvalidLinePolicy
+		expected string
+	}{
+		{
+			name:     "skip",
+			policy:   InvalidLineSkip,
+			expected: "{\"name\":\"John\",\"age\":30}\n[1,2]\n{\"b\":true}\n",
+		},
+		{
+			name:     "null",
+			policy:   InvalidLineNull,
+			expected: "{\"name\":\"John\",\"age\":30}\n[1,2]\nnull\n{\"b\":true}\n",
+		},
+		{
+			name:     "abort",
+			policy:   InvalidLineAbort,
+			expected: "{\"name\":\"John\",\"age\":30}\n[1,2]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultNDJSONOptions()
+			opts.InvalidLine = tt.policy
+
+			var out bytes.Buffer
+			err := RepairNDJSON(strings.NewReader(input), &out, opts)
+
+			var lineErr *LineError
+			if !errors.As(err, &lineErr) {
+				t.Fatalf("RepairNDJSON() error = %v, expected a *LineError", err)
+			}
+			if lineErr.Line != 4 {
+				t.Errorf("Line = %d, expected 4", lineErr.Line)
+			}
+			var repairErr *RepairError
+			if !errors.As(err, &repairErr) || repairErr.Kind != UnexpectedCharacter {
+				t.Errorf("RepairNDJSON() error = %v, expected an UnexpectedCharacter error", err)
+			}
+
+			if out.String() != tt.expected {
+				t.Errorf("RepairNDJSON() = %q, expected %q", out.String(), tt.expected)
+			}
+		})
+	}
+}
+
+func TestRepairNDJSONValid(t *testing.T) {
+	input := "{\"a\": 1}\n{\"b\": [1, 2,]}"
+
+	var out bytes.Buffer
+	if err := RepairNDJSON(strings.NewReader(input), &out, DefaultNDJSONOptions()); err != nil {
+		t.Fatalf("RepairNDJSON() error = %v", err)
+	}
+
+	if expected := "{\"a\":1}\n{\"b\":[1,2]}\n"; out.String() != expected {
+		t.Errorf("RepairNDJSON() = %q, expected %q", out.String(), expected)
+	}
+}
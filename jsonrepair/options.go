@@ -0,0 +1,125 @@
+package jsonrepair
+
+// Options controls which families of repairs are applied. When a repair is
+// disabled, input that would need it is reported as an error instead of being
+// silently rewritten.
+//
+// The zero value disables every repair; use DefaultOptions to start from the
+// configuration used by Repair.
+type Options struct {
+	// Comments strips JavaScript comments (// and /* */).
+	Comments bool
+	// TrailingCommas removes trailing commas in objects and arrays.
+	TrailingCommas bool
+	// SingleQuotes converts single quoted strings to double quoted strings.
+	SingleQuotes bool
+	// SpecialQuotes converts strings delimited by typographic, prime or
+	// full-width quotes, guillemets or backticks to double quoted strings.
+	SpecialQuotes bool
+	// MissingQuotes repairs strings with a missing opening quote, closes
+	// strings with a missing closing quote where the string most likely ends,
+	// and escapes quotes inside strings.
+	MissingQuotes bool
+	// UnquotedStrings adds missing quotes around keys and string values.
+	UnquotedStrings bool
+	// MissingSeparators inserts missing colons between keys and values and
+	// missing commas between object members and array elements.
+	MissingSeparators bool
+	// MismatchedBrackets closes the innermost object or array at a closing
+	// bracket of an enclosing one, like the ']' in [{"a": 1], and removes
+	// closing brackets that match no open object or array.
+	MismatchedBrackets bool
+	// Truncation closes strings, objects and arrays cut off by the end of input.
+	Truncation bool
+	// PythonConstants converts True, False and None to true, false and null.
+	PythonConstants bool
+	// MongoDBTypes strips NumberLong, NumberInt, ISODate and ObjectId wrappers.
+	MongoDBTypes bool
+	// JSONP strips a JSONP wrapper like callback({...}).
+	JSONP bool
+	// CodeFences strips a markdown code fence like ```json ... ```.
+	CodeFences bool
+	// Ellipsis removes ... placeholders from arrays.
+	Ellipsis bool
+	// StringConcatenation joins strings concatenated with +.
+	StringConcatenation bool
+	// Escapes repairs invalid escape sequences in strings, like stray
+	// backslashes, \x41, octal escapes, incomplete \u escapes and lone
+	// surrogates.
+	Escapes bool
+	// ControlCharacters escapes raw control characters like newlines and tabs
+	// in strings.
+	ControlCharacters bool
+	// LenientNumbers repairs numbers like +1, .5, 1., 1e, 0x1F, 0o17, 0b101,
+	// 1_000 and 007.
+	LenientNumbers bool
+	// NonFinite controls how NaN, Infinity and undefined are repaired.
+	NonFinite NonFinitePolicy
+	// InvalidUTF8 replaces invalid UTF-8 sequences in strings with U+FFFD.
+	InvalidUTF8 bool
+	// InvalidWhitespace removes whitespace that JSON does not allow, like
+	// non-breaking and full-width spaces, around values.
+	InvalidWhitespace bool
+	// TrailingContent controls what happens to content after the root value.
+	TrailingContent TrailingContentPolicy
+}
+
+// DefaultOptions returns the options used by Repair, with every repair enabled.
+func DefaultOptions() Options {
+	return Options{
+		Comments:            true,
+		TrailingCommas:      true,
+		SingleQuotes:        true,
+		SpecialQuotes:       true,
+		MissingQuotes:       true,
+		UnquotedStrings:     true,
+		MissingSeparators:   true,
+		MismatchedBrackets:  true,
+		Truncation:          true,
+		PythonConstants:     true,
+		MongoDBTypes:        true,
+		JSONP:               true,
+		CodeFences:          true,
+		Ellipsis:            true,
+		StringConcatenation: true,
+		Escapes:             true,
+		ControlCharacters:   true,
+		LenientNumbers:      true,
+		NonFinite:           NonFiniteNull,
+		InvalidUTF8:         true,
+		InvalidWhitespace:   true,
+		TrailingContent:     TrailingContentDiscard,
+	}
+}
+
+// NonFinitePolicy controls how values that JSON cannot represent are
+// repaired: JavaScript NaN, Infinity, -Infinity and undefined, and Python
+// nan, inf and -inf
+type NonFinitePolicy int
+
+const (
+	// NonFiniteError fails with an error, like a disabled repair
+	NonFiniteError NonFinitePolicy = iota
+	// NonFiniteNull replaces the value with null
+	NonFiniteNull
+	// NonFiniteString replaces the value with a string holding its text,
+	// like "NaN"
+	NonFiniteString
+)
+
+// TrailingContentPolicy controls how content after the root value, like the
+// oops in {"a": 1} oops, is repaired
+type TrailingContentPolicy int
+
+const (
+	// TrailingContentError fails with an error, like a disabled repair
+	TrailingContentError TrailingContentPolicy = iota
+	// TrailingContentArray parses the content as more values, separated by
+	// whitespace, commas or nothing at all, and wraps them together with the
+	// root value in an array. RepairStream and Repairer keep the root value
+	// in memory until the next one is found, since the opening '[' is only
+	// known then.
+	TrailingContentArray
+	// TrailingContentDiscard keeps the root value and drops the content
+	TrailingContentDiscard
+)
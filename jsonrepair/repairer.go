@@ -0,0 +1,83 @@
+package jsonrepair
+
+import "strings"
+
+// Repairer repairs a JSON document that arrives in chunks, such as a model
+// response streamed token by token. Snapshot renders everything written so
+// far as valid JSON, closing whatever is still open.
+//
+// Parser state is kept between snapshots: only the input after the last
+// complete object member or array element is parsed again, and a string cut
+// off by the end of a chunk is continued from where it was cut off, so
+// parsing grows with the size of the chunks rather than with the size of the
+// document.
+// Each snapshot is still a copy of the whole repaired document, so taking one
+// after every chunk costs time linear in the size of the document per chunk.
+type Repairer struct {
+	opts Options
+
+	// committed is the repaired output up to the resume checkpoint
+	committed []byte
+	// pending is the input from the resume checkpoint on
+	pending []byte
+	resume  *checkpoint
+	// progress is how far the string cut off by the last snapshot was
+	// parsed, with its offsets relative to pending
+	progress stringProgress
+	// pos locates pending[0] in the input
+	pos position
+}
+
+// NewRepairer returns a Repairer that applies the repairs enabled in opts
+func NewRepairer(opts Options) *Repairer {
+	return &Repairer{opts: opts}
+}
+
+// Write appends a chunk of the document. It never fails.
+func (r *Repairer) Write(chunk []byte) (int, error) {
+	r.pending = append(r.pending, chunk...)
+	return len(chunk), nil
+}
+
+// Snapshot returns valid JSON for the document written so far, as Repair
+// would for the same text
+func (r *Repairer) Snapshot() (string, error) {
+	p := &parser{
+		input:     bytesToString(r.pending),
+		opts:      r.opts,
+		resumable: true,
+		progress:  r.progress,
+	}
+	var err error
+	if r.resume != nil {
+		err = p.resume(r.resume)
+	} else {
+		err = p.parseRoot()
+	}
+
+	var snapshot strings.Builder
+	snapshot.Grow(len(r.committed) + p.output.Len())
+	snapshot.Write(r.committed)
+	snapshot.Write(p.output.Bytes())
+	if err != nil {
+		err = r.pos.locate(err)
+	}
+
+	// Everything before the last checkpoint stays the same whatever is
+	// written next
+	r.progress = p.progress
+	if p.last.index > 0 {
+		c := p.last
+		r.committed = append(r.committed, p.output.Bytes()[:c.output]...)
+		r.pos.advance(r.pending[:c.index])
+		r.pending = r.pending[:copy(r.pending, r.pending[c.index:])]
+		r.progress.rebase(c.index)
+		c.index = 0
+		r.resume = &c
+	}
+
+	if err != nil {
+		return "", err
+	}
+	return snapshot.String(), nil
+}
@@ -0,0 +1,131 @@
+package jsonrepair
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRepairerSnapshot(t *testing.T) {
+	inputs := []string{
+		`{"name": "John", "age": 30, "tags": ["a", "b"]}`,
+		`{name: 'John', address: {city: 'NYC', zip: '10001'}, ok: True}`,
+		`[{"a": 1}, {"b": [1, 2, 3,]}, ..., {"c": "x" + "y"}]`,
+		"{\"a\": 1, // comment\n\"b\": /* c */ 2}",
+		"```json\n{\"answer\": [1, 2, 3], \"done\": false}\n```",
+		`{"_id": ObjectId("507f1f77bcf86cd799439011"), "n": NumberLong("1")}`,
+		"{\"a\" 1\n\"b\": [1 2 3]\n\"c\" {\"d\" true}}",
+		`{"path": "C:\temp\x41", "emoji": "\uD83D\uDE00", "octal": '\101'}`,
+		"{\"poem\": \"roses\n\tare red\", 'more': 'violets\nare blue'}",
+		`{“name”: ‘John’, «city»: "Zürich" + ‘!’}`,
+		"{\"a\": \"hello, \"b\": \"x\ny\n  \"c\": [\"d, \"e\"],\n \"f\": \"https://x.com/a}",
+		`{'text': 'it's "fine"', "u": "a" + "b, "v": "c "w": 1}`,
+		`{"name": John Smith", first name": "Jane", "c": b"c}`,
+		"{status: in progress, owner: Jane Doe  // note\n, path: C:\\temp\n}",
+		"{url: https://example.com/a, https://x.y/z: a/b/c // note\n, glob: src/*.go /* c */}",
+		`{"a": NaN, "b": [Infinity, -Infinity, undefined], "c": -inf // x` + "\n}",
+		`{"a": [+1, .5, 1., 1e, 0x1F, 0o17, 0b101, 1_000, 007, 1.2.3, 12.5e-3], "b": 12.`,
+		"{名前: '山田', city:\u3000Zürich, \"emoji\": \"😀\"}",
+		`{"a": [test, now, nullable, True, null], "b": fals`,
+		`[{"a": [1, {"b": 2]}, [3}, 4], 5]]`,
+		`{"answer": "a long answer with \"quotes\", escapes like \n, \u00e9 and \uD83D\uDE00, then a path C:\temp\new", "b": 1}`,
+		"{\"answer\": \"a long answer that quotes \"someone\" and keeps going\nover “lines” 😀 until a comma, \"next\": 2}",
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			r := NewRepairer(DefaultOptions())
+			for i := 0; i < len(input); i++ {
+				r.Write([]byte{input[i]})
+
+				expected, expectedErr := Repair(input[:i+1])
+				snapshot, err := r.Snapshot()
+				if (err != nil) != (expectedErr != nil) {
+					t.Fatalf("Snapshot() after %q error = %v, expected %v", input[:i+1], err, expectedErr)
+				}
+				if snapshot != expected {
+					t.Fatalf("Snapshot() after %q = %s, expected %s", input[:i+1], snapshot, expected)
+				}
+			}
+		})
+	}
+}
+
+func TestRepairerChunks(t *testing.T) {
+	r := NewRepairer(DefaultOptions())
+	chunks := []string{`{"items": [`, `{"id": 1}, {"id"`, `: 2}, {"id": 3`, `}], "total": 3}`}
+	expected := []string{
+		`{"items":[]}`,
+		`{"items":[{"id":1},{"id":null}]}`,
+		`{"items":[{"id":1},{"id":2},{"id":3}]}`,
+		`{"items":[{"id":1},{"id":2},{"id":3}],"total":3}`,
+	}
+
+	for i, chunk := range chunks {
+		r.Write([]byte(chunk))
+		snapshot, err := r.Snapshot()
+		if err != nil {
+			t.Fatalf("Snapshot() error = %v", err)
+		}
+		if snapshot != expected[i] {
+			t.Errorf("Snapshot() = %s, expected %s", snapshot, expected[i])
+		}
+	}
+
+	if len(r.pending) >= len(strings.Join(chunks, "")) {
+		t.Errorf("Repairer kept all %d bytes of input, expected completed elements to be dropped", len(r.pending))
+	}
+}
+
+func TestRepairerLongString(t *testing.T) {
+	input := `{"answer": "` + strings.Repeat("word ", 2000)
+	start := len(`{"answer": `)
+
+	r := NewRepairer(DefaultOptions())
+	for i := 0; i < len(input); i += 4 {
+		r.Write([]byte(input[i:min(i+4, len(input))]))
+		if _, err := r.Snapshot(); err != nil {
+			t.Fatalf("Snapshot() error = %v", err)
+		}
+
+		// The string is continued from close to the end of what was written
+		if end := r.pos.offset + len(r.pending); end > start+stringLookahead+4 {
+			if offset := r.pos.offset + r.progress.start; offset != start {
+				t.Fatalf("progress starts at %d, expected %d", offset, start)
+			}
+			if r.progress.index < len(r.pending)-stringLookahead-4 {
+				t.Fatalf("progress is at %d after %d bytes, expected at most %d bytes before the end", r.progress.index, len(r.pending), stringLookahead+4)
+			}
+		}
+	}
+
+	// The next snapshot continues from the progress rather than parsing the
+	// string again
+	copy(r.progress.output, "WORD")
+	snapshot, err := r.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	if !strings.HasPrefix(snapshot, `{"answer":"WORD word`) {
+		t.Errorf("Snapshot() = %.30s..., expected it to continue from the progress", snapshot)
+	}
+}
+
+func TestRepairerError(t *testing.T) {
+	r := NewRepairer(DefaultOptions())
+	r.Write([]byte("[1,\n 2,\n"))
+	if _, err := r.Snapshot(); err != nil {
+		t.Fatalf("Snapshot() error = %v", err)
+	}
+	r.Write([]byte(" @]"))
+
+	_, err := r.Snapshot()
+
+	var repairErr *RepairError
+	if !errors.As(err, &repairErr) {
+		t.Fatalf("Snapshot() error = %v, expected a *RepairError", err)
+	}
+	if repairErr.Offset != 9 || repairErr.Line != 3 || repairErr.Column != 2 {
+		t.Errorf("position = %d (%d:%d), expected 9 (3:2)", repairErr.Offset, repairErr.Line, repairErr.Column)
+	}
+}
@@ -0,0 +1,152 @@
+package jsonrepair
+
+import "fmt"
+
+// ActionKind identifies the kind of repair applied to the input
+type ActionKind int
+
+const (
+	// AddedQuotes means quotes were added around an unquoted key or value
+	AddedQuotes ActionKind = iota
+	// ReplacedQuotes means non-standard quotes were replaced by double quotes
+	ReplacedQuotes
+	// RemovedComment means a // or /* */ comment was removed
+	RemovedComment
+	// RemovedTrailingComma means a trailing comma in an object or array was removed
+	RemovedTrailingComma
+	// ClosedTruncatedObject means a missing '}' was added at the end of input
+	ClosedTruncatedObject
+	// ClosedTruncatedArray means a missing ']' was added at the end of input
+	ClosedTruncatedArray
+	// ClosedTruncatedString means a missing closing quote was added at the end of input
+	ClosedTruncatedString
+	// AddedMissingValue means null was added for a value missing at the end of input
+	AddedMissingValue
+	// ConvertedPythonConstant means True, False or None was converted
+	ConvertedPythonConstant
+	// RepairedNumber means a number was rewritten as valid JSON, like 0x1F
+	// as 31, or cut back to its valid prefix at the end of input
+	RepairedNumber
+	// ConvertedNonFinite means NaN, Infinity or undefined was converted to
+	// null or a string, as set by Options.NonFinite
+	ConvertedNonFinite
+	// StrippedMongoType means a MongoDB type wrapper like ObjectId(...) was removed
+	StrippedMongoType
+	// StrippedJSONP means a JSONP wrapper like callback(...) was removed
+	StrippedJSONP
+	// StrippedCodeFence means a markdown code fence was removed
+	StrippedCodeFence
+	// RemovedEllipsis means a ... placeholder was removed from an array
+	RemovedEllipsis
+	// ConcatenatedStrings means strings joined with + were merged
+	ConcatenatedStrings
+	// ReplacedInvalidUTF8 means an invalid UTF-8 sequence in a string was
+	// replaced by U+FFFD
+	ReplacedInvalidUTF8
+	// RemovedWhitespace means whitespace that JSON does not allow, like
+	// U+00A0 or U+3000, was removed between tokens
+	RemovedWhitespace
+	// InsertedColon means a missing ':' was added between a key and its value
+	InsertedColon
+	// InsertedComma means a missing ',' was added between object members or
+	// array elements
+	InsertedComma
+	// RepairedEscape means an invalid escape sequence in a string was
+	// rewritten, or dropped when cut off by the end of input
+	RepairedEscape
+	// EscapedControlCharacter means a raw control character in a string, like
+	// a newline, was escaped
+	EscapedControlCharacter
+	// AddedClosingQuote means a missing closing quote was added where the
+	// string most likely ends, before the end of input
+	AddedClosingQuote
+	// AddedOpeningQuote means a missing opening quote was added to a string
+	// that has a closing quote
+	AddedOpeningQuote
+	// EscapedQuote means a quote inside a string was escaped
+	EscapedQuote
+	// CompletedKeyword means a keyword cut off by the end of input, like
+	// tru, was completed
+	CompletedKeyword
+	// ClosedMismatchedBracket means an object or array was closed at a
+	// closing bracket of an enclosing one
+	ClosedMismatchedBracket
+	// RemovedClosingBracket means a closing bracket that matches no open
+	// object or array was removed
+	RemovedClosingBracket
+	// DiscardedTrailingContent means content after the root value was
+	// dropped, as set by Options.TrailingContent
+	DiscardedTrailingContent
+	// WrappedRootValues means values following the root value were wrapped
+	// together with it in an array, as set by Options.TrailingContent
+	WrappedRootValues
+)
+
+var actionKindNames = [...]string{
+	AddedQuotes:              "AddedQuotes",
+	ReplacedQuotes:           "ReplacedQuotes",
+	RemovedComment:           "RemovedComment",
+	RemovedTrailingComma:     "RemovedTrailingComma",
+	ClosedTruncatedObject:    "ClosedTruncatedObject",
+	ClosedTruncatedArray:     "ClosedTruncatedArray",
+	ClosedTruncatedString:    "ClosedTruncatedString",
+	AddedMissingValue:        "AddedMissingValue",
+	ConvertedPythonConstant:  "ConvertedPythonConstant",
+	RepairedNumber:           "RepairedNumber",
+	ConvertedNonFinite:       "ConvertedNonFinite",
+	StrippedMongoType:        "StrippedMongoType",
+	StrippedJSONP:            "StrippedJSONP",
+	StrippedCodeFence:        "StrippedCodeFence",
+	RemovedEllipsis:          "RemovedEllipsis",
+	ConcatenatedStrings:      "ConcatenatedStrings",
+	ReplacedInvalidUTF8:      "ReplacedInvalidUTF8",
+	RemovedWhitespace:        "RemovedWhitespace",
+	InsertedColon:            "InsertedColon",
+	InsertedComma:            "InsertedComma",
+	RepairedEscape:           "RepairedEscape",
+	EscapedControlCharacter:  "EscapedControlCharacter",
+	AddedClosingQuote:        "AddedClosingQuote",
+	AddedOpeningQuote:        "AddedOpeningQuote",
+	EscapedQuote:             "EscapedQuote",
+	CompletedKeyword:         "CompletedKeyword",
+	ClosedMismatchedBracket:  "ClosedMismatchedBracket",
+	RemovedClosingBracket:    "RemovedClosingBracket",
+	DiscardedTrailingContent: "DiscardedTrailingContent",
+	WrappedRootValues:        "WrappedRootValues",
+}
+
+func (k ActionKind) String() string {
+	if k >= 0 && int(k) < len(actionKindNames) {
+		return actionKindNames[k]
+	}
+	return fmt.Sprintf("ActionKind(%d)", int(k))
+}
+
+// Action describes a single repair applied to the input
+type Action struct {
+	Kind ActionKind
+	// Start and End are the byte offsets of the repaired span in the input.
+	// They are equal when text was inserted rather than rewritten.
+	Start int
+	End   int
+}
+
+func (a Action) String() string {
+	return fmt.Sprintf("%s at %d-%d", a.Kind, a.Start, a.End)
+}
+
+// RepairWithReport repairs a malformed JSON string like RepairWithOptions and
+// also returns every repair that was applied, in the order it was made. An
+// empty list means the input was already valid JSON.
+func RepairWithReport(input string, opts Options) (string, []Action, error) {
+	p := &parser{
+		input:  input,
+		opts:   opts,
+		report: true,
+	}
+	output, err := p.parse()
+	if err != nil {
+		return "", nil, err
+	}
+	return output, p.actions, nil
+}
@@ -0,0 +1,229 @@
+package jsonrepair
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRepairWithReport(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []Action
+	}{
+		{
+			name:     "already valid JSON",
+			input:    `{"name": "John", "tags": [1, 2]}`,
+			expected: nil,
+		},
+		{
+			name:  "unquoted key and single quotes",
+			input: `{name: 'John'}`,
+			expected: []Action{
+				{Kind: AddedQuotes, Start: 1, End: 5},
+				{Kind: ReplacedQuotes, Start: 7, End: 8},
+				{Kind: ReplacedQuotes, Start: 12, End: 13},
+			},
+		},
+		{
+			name:  "comment and trailing comma",
+			input: `{"a": 1, /* c */ "b": [1,],}`,
+			expected: []Action{
+				{Kind: RemovedComment, Start: 9, End: 16},
+				{Kind: RemovedTrailingComma, Start: 24, End: 25},
+				{Kind: RemovedTrailingComma, Start: 26, End: 27},
+			},
+		},
+		{
+			name:  "truncated",
+			input: `{"a": [1, "x`,
+			expected: []Action{
+				{Kind: ClosedTruncatedString, Start: 12, End: 12},
+				{Kind: ClosedTruncatedArray, Start: 12, End: 12},
+				{Kind: ClosedTruncatedObject, Start: 12, End: 12},
+			},
+		},
+		{
+			name:  "missing value",
+			input: `{"a":`,
+			expected: []Action{
+				{Kind: AddedMissingValue, Start: 5, End: 5},
+				{Kind: ClosedTruncatedObject, Start: 5, End: 5},
+			},
+		},
+		{
+			name:  "python constant and MongoDB type",
+			input: `[True, ObjectId("x")]`,
+			expected: []Action{
+				{Kind: ConvertedPythonConstant, Start: 1, End: 5},
+				{Kind: StrippedMongoType, Start: 7, End: 20},
+			},
+		},
+		{
+			name:  "JSONP",
+			input: `cb({"a": 1})`,
+			expected: []Action{
+				{Kind: StrippedJSONP, Start: 0, End: 3},
+				{Kind: StrippedJSONP, Start: 11, End: 12},
+			},
+		},
+		{
+			name:  "code fence",
+			input: "```json\n[1]\n```",
+			expected: []Action{
+				{Kind: StrippedCodeFence, Start: 0, End: 8},
+				{Kind: StrippedCodeFence, Start: 12, End: 15},
+			},
+		},
+		{
+			name:  "ellipsis",
+			input: `[1, ...]`,
+			expected: []Action{
+				{Kind: RemovedEllipsis, Start: 4, End: 7},
+			},
+		},
+		{
+			name:  "concatenated strings",
+			input: `"a" + "b"`,
+			expected: []Action{
+				{Kind: ConcatenatedStrings, Start: 2, End: 7},
+			},
+		},
+		{
+			name:  "missing colon and commas",
+			input: "{\"a\" 1\n\"b\": [1 2]}",
+			expected: []Action{
+				{Kind: InsertedColon, Start: 4, End: 4},
+				{Kind: InsertedComma, Start: 6, End: 6},
+				{Kind: InsertedComma, Start: 14, End: 14},
+			},
+		},
+		{
+			name:  "escapes",
+			input: `["\x41\n\q"]`,
+			expected: []Action{
+				{Kind: RepairedEscape, Start: 2, End: 6},
+				{Kind: RepairedEscape, Start: 8, End: 9},
+			},
+		},
+		{
+			name:  "control characters",
+			input: "[\"a\tb\nc\"]",
+			expected: []Action{
+				{Kind: EscapedControlCharacter, Start: 3, End: 4},
+				{Kind: EscapedControlCharacter, Start: 5, End: 6},
+			},
+		},
+		{
+			name:  "missing closing quote",
+			input: `{"a": "b, "c": "say "hi" now"}`,
+			expected: []Action{
+				{Kind: AddedClosingQuote, Start: 8, End: 8},
+				{Kind: EscapedQuote, Start: 20, End: 21},
+				{Kind: EscapedQuote, Start: 23, End: 24},
+			},
+		},
+		{
+			name:  "missing opening quote",
+			input: `{"a": b c”}`,
+			expected: []Action{
+				{Kind: AddedOpeningQuote, Start: 6, End: 6},
+				{Kind: ReplacedQuotes, Start: 9, End: 12},
+			},
+		},
+		{
+			name:  "numbers",
+			input: `[0x1F, 1, .5, 2.`,
+			expected: []Action{
+				{Kind: RepairedNumber, Start: 1, End: 5},
+				{Kind: RepairedNumber, Start: 10, End: 12},
+				{Kind: RepairedNumber, Start: 14, End: 16},
+				{Kind: ClosedTruncatedArray, Start: 16, End: 16},
+			},
+		},
+		{
+			name:  "non-finite numbers",
+			input: `[NaN, -Infinity]`,
+			expected: []Action{
+				{Kind: ConvertedNonFinite, Start: 1, End: 4},
+				{Kind: ConvertedNonFinite, Start: 6, End: 15},
+			},
+		},
+		{
+			name:  "truncated keyword",
+			input: `[true, fa`,
+			expected: []Action{
+				{Kind: CompletedKeyword, Start: 7, End: 9},
+				{Kind: ClosedTruncatedArray, Start: 9, End: 9},
+			},
+		},
+		{
+			name:  "mismatched brackets",
+			input: `{"a": [1}}`,
+			expected: []Action{
+				{Kind: ClosedMismatchedBracket, Start: 8, End: 8},
+				{Kind: RemovedClosingBracket, Start: 9, End: 10},
+			},
+		},
+		{
+			name:  "trailing content",
+			input: `{"a": 1} oops`,
+			expected: []Action{
+				{Kind: DiscardedTrailingContent, Start: 9, End: 13},
+			},
+		},
+		{
+			name:  "typographic quotes",
+			input: `[“a”]`,
+			expected: []Action{
+				{Kind: ReplacedQuotes, Start: 1, End: 4},
+				{Kind: ReplacedQuotes, Start: 5, End: 8},
+			},
+		},
+		{
+			name:  "invalid UTF-8 and non-ASCII whitespace",
+			input: "[\u00a0\"a\xffb\"]",
+			expected: []Action{
+				{Kind: RemovedWhitespace, Start: 1, End: 3},
+				{Kind: ReplacedInvalidUTF8, Start: 5, End: 6},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, actions, err := RepairWithReport(tt.input, DefaultOptions())
+			if err != nil {
+				t.Fatalf("RepairWithReport() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(actions, tt.expected) {
+				t.Errorf("RepairWithReport() actions = %v, expected %v", actions, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRepairWithReportRootValues(t *testing.T) {
+	opts := DefaultOptions()
+	opts.TrailingContent = TrailingContentArray
+
+	_, actions, err := RepairWithReport(`{"a": 1}, {"b": 2}`, opts)
+	if err != nil {
+		t.Fatalf("RepairWithReport() error = %v", err)
+	}
+
+	expected := []Action{{Kind: WrappedRootValues, Start: 10, End: 18}}
+	if !reflect.DeepEqual(actions, expected) {
+		t.Errorf("RepairWithReport() actions = %v, expected %v", actions, expected)
+	}
+}
+
+func TestActionKindString(t *testing.T) {
+	if got := RemovedTrailingComma.String(); got != "RemovedTrailingComma" {
+		t.Errorf("String() = %q, expected %q", got, "RemovedTrailingComma")
+	}
+	if got := ActionKind(-1).String(); got != "ActionKind(-1)" {
+		t.Errorf("String() = %q, expected %q", got, "ActionKind(-1)")
+	}
+}
@@ -0,0 +1,205 @@
+package jsonrepair
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"unicode/utf8"
+)
+
+// streamChunkSize is the minimum number of bytes requested from the input at
+// a time
+const streamChunkSize = 32 * 1024
+
+// streamLookahead is how close to the end of the buffered input an error must
+// be to retry with more input, since it may be caused by a token cut off by
+// the end of the buffer rather than by the document itself
+const streamLookahead = 4 * 1024
+
+// RepairStream repairs a malformed JSON document read from r and writes valid
+// JSON to w. Input is read incrementally and output is written as soon as it
+// is known, so memory use is bounded by the size of the largest object member
+// or array element rather than by the size of the document.
+func RepairStream(w io.Writer, r io.Reader, opts Options) error {
+	_, err := io.Copy(w, newStreamReader(r, opts))
+	return err
+}
+
+// streamReader reads the repaired JSON of the document read from r
+type streamReader struct {
+	r    io.Reader
+	opts Options
+
+	// buf holds the input that has not been consumed yet
+	buf []byte
+	eof bool
+	// resume is the checkpoint to continue parsing from, with its index
+	// relative to buf, or nil before the first one was reached
+	resume *checkpoint
+	// out holds repaired output not returned by Read yet
+	out []byte
+	err error
+
+	// pos locates buf[0] in the input
+	pos position
+
+	// values reads a series of root values like json.Decoder: each one is
+	// repaired in turn and followed by a newline instead of discarding what
+	// follows the first one, and input without a value is io.EOF
+	values bool
+}
+
+func newStreamReader(r io.Reader, opts Options) *streamReader {
+	return &streamReader{r: r, opts: opts}
+}
+
+func (s *streamReader) Read(b []byte) (int, error) {
+	for len(s.out) == 0 && s.err == nil {
+		s.err = s.step()
+	}
+	if len(s.out) > 0 {
+		n := copy(b, s.out)
+		s.out = s.out[n:]
+		return n, nil
+	}
+	return 0, s.err
+}
+
+// step reads more input and repairs as much of the buffered input as can no
+// longer change. It returns io.EOF once the whole document was repaired.
+func (s *streamReader) step() error {
+	if err := s.fill(); err != nil {
+		return err
+	}
+
+	p := &parser{
+		input:     string(s.buf),
+		opts:      s.opts,
+		resumable: !s.eof,
+	}
+	if s.values && s.eof && s.resume == nil {
+		p.skipWhitespaceAndComments()
+		if p.index >= len(p.input) {
+			return io.EOF
+		}
+		p.index, p.actions = 0, nil
+	}
+
+	var err error
+	if s.resume != nil {
+		err = p.resume(s.resume)
+	} else {
+		err = p.parseRoot()
+	}
+
+	if s.eof || (err == nil && p.complete) {
+		if err != nil {
+			return s.pos.locate(err)
+		}
+		s.out = p.output.Bytes()
+
+		// At the end of input nothing after the root value can change it.
+		// Before it, more values may still follow.
+		if s.values && (p.rootEnd < len(s.buf) || !s.eof) {
+			// Repair the values after this one with a new parser
+			s.out = append(s.out, '\n')
+			s.pos.advance(s.buf[:p.rootEnd])
+			s.buf = s.buf[:copy(s.buf, s.buf[p.rootEnd:])]
+			s.resume = nil
+			return nil
+		}
+
+		s.buf = nil
+		return io.EOF
+	}
+
+	// An error far from the end of the buffer does not depend on input that
+	// was not read yet, unless the parser looked past the end of the buffer
+	// before reaching it
+	var repairErr *RepairError
+	if errors.As(err, &repairErr) && repairErr.Offset < len(s.buf)-streamLookahead && !p.peekedPastEnd {
+		return s.pos.locate(err)
+	}
+
+	// Everything before the last checkpoint is final. Keep the rest of the
+	// input to parse again once more of it is available.
+	if p.last.index > 0 {
+		c := p.last
+		s.out = p.output.Bytes()[:c.output]
+		s.pos.advance(s.buf[:c.index])
+		s.buf = s.buf[:copy(s.buf, s.buf[c.index:])]
+		c.index = 0
+		s.resume = &c
+	}
+	return nil
+}
+
+// fill appends the next chunk of input to buf. The chunk is at least as
+// large as the input already buffered, so that an element spanning many
+// chunks is parsed a logarithmic number of times.
+func (s *streamReader) fill() error {
+	size := streamChunkSize
+	if len(s.buf) > size {
+		size = len(s.buf)
+	}
+
+	if s.eof {
+		return nil
+	}
+
+	n := len(s.buf)
+	if cap(s.buf)-n < size {
+		buf := make([]byte, n, n+size)
+		copy(buf, s.buf)
+		s.buf = buf
+	}
+
+	// Like bufio, give up on readers that keep returning no data
+	for i := 0; i < 100; i++ {
+		m, err := s.r.Read(s.buf[n:cap(s.buf)])
+		s.buf = s.buf[:n+m]
+		if errors.Is(err, io.EOF) {
+			s.eof = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if m > 0 {
+			return nil
+		}
+	}
+	return io.ErrNoProgress
+}
+
+// position locates the start of a buffer in the whole input, to report
+// errors relative to the input instead of to the buffer
+type position struct {
+	offset int
+	line   int
+	column int
+}
+
+// advance moves the position past consumed
+func (pos *position) advance(consumed []byte) {
+	if i := bytes.LastIndexByte(consumed, '\n'); i >= 0 {
+		pos.line += bytes.Count(consumed, []byte{'\n'})
+		pos.column = utf8.RuneCount(consumed[i+1:])
+	} else {
+		pos.column += utf8.RuneCount(consumed)
+	}
+	pos.offset += len(consumed)
+}
+
+// locate makes the position of a RepairError relative to the whole input
+func (pos *position) locate(err error) error {
+	var repairErr *RepairError
+	if errors.As(err, &repairErr) {
+		if repairErr.Line == 1 {
+			repairErr.Column += pos.column
+		}
+		repairErr.Line += pos.line
+		repairErr.Offset += pos.offset
+	}
+	return err
+}
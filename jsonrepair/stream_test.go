@@ -0,0 +1,227 @@
+package jsonrepair
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestRepairStream(t *testing.T) {
+	inputs := []string{
+		`{"name": "John"}`,
+		`{name: 'John', age: 30, address: {city: 'NYC', zip: '10001'}}`,
+		`[{name: 'John'}, {name: 'Jane'}, ..., {name: 'Joe'},]`,
+		"{\"a\": 1, // comment\n\"b\": [1, 2, /* c */ 3,],}",
+		`{"a": {"b": [1, {"c": "x`,
+		`{"a": 1, "b":`,
+		`{"text": "hello" + "world", "more": 'a' + 'b'}`,
+		`{"_id": ObjectId("507f1f77bcf86cd799439011"), "n": NumberLong({"$numberLong": "1"})}`,
+		`callback({"a": [1, 2, 3]})`,
+		"```json\n{\"a\": [1, 2]}\n```",
+		`{"active": True, "deleted": False, "data": None}`,
+		`[1, 2, 3] trailing`,
+		`"just a string"`,
+		`12345`,
+		"{\"a\": \"hello, \"b\": \"x\ny\n  \"c\": [\"d, \"e\"],\n \"f\": \"https://x.com/a}",
+		`{'text': 'it's "fine"', "u": "a" + "b, "v": "c "w": 1}`,
+		`{"name": John Smith", first name": "Jane", "c": b"c}`,
+		"{status: in progress, owner: Jane Doe  // note\n, path: C:\\temp\n}",
+		"{url: https://example.com/a, https://x.y/z: a/b/c // note\n, glob: src/*.go /* c */}",
+		`{"a": NaN, "b": [Infinity, -Infinity, undefined], "c": -inf // x` + "\n}",
+		`{"a": [+1, .5, 1., 1e, 0x1F, 0o17, 0b101, 1_000, 007, 1.2.3, 12.5e-3], "b": 12.`,
+		"{名前: '山田', city:\u3000Zürich, \"emoji\": \"😀\"}",
+		`{"a": [test, now, nullable, True, null], "b": fals`,
+		`[{"a": [1, {"b": 2]}, [3}, 4], 5]]`,
+		"```json\ntrueé",
+		"cb(trueé)",
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			expected, err := Repair(input)
+			if err != nil {
+				t.Fatalf("Repair() error = %v", err)
+			}
+
+			var out bytes.Buffer
+			if err := RepairStream(&out, iotest.OneByteReader(strings.NewReader(input)), DefaultOptions()); err != nil {
+				t.Fatalf("RepairStream() error = %v", err)
+			}
+
+			if out.String() != expected {
+				t.Errorf("RepairStream() = %s, expected %s", out.String(), expected)
+			}
+		})
+	}
+}
+
+func TestRepairStreamTrailingContent(t *testing.T) {
+	input := `{"a": [1, 2]} {"b": "x"} oops`
+
+	for _, policy := range []TrailingContentPolicy{TrailingContentError, TrailingContentArray, TrailingContentDiscard} {
+		opts := DefaultOptions()
+		opts.TrailingContent = policy
+
+		expected, expectedErr := RepairWithOptions(input, opts)
+		var out bytes.Buffer
+		err := RepairStream(&out, iotest.OneByteReader(strings.NewReader(input)), opts)
+		if (err != nil) != (expectedErr != nil) {
+			t.Fatalf("RepairStream() with policy %d error = %v, expected %v", policy, err, expectedErr)
+		}
+		if err == nil && out.String() != expected {
+			t.Errorf("RepairStream() with policy %d = %s, expected %s", policy, out.String(), expected)
+		}
+	}
+}
+
+func TestRepairStreamExtraBrackets(t *testing.T) {
+	// Without MismatchedBrackets, closing brackets after the root value are
+	// discarded with the trailing content
+	opts := DefaultOptions()
+	opts.MismatchedBrackets = false
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"a": 1} }`, `{"a":1}`},
+		{"[1]]\n[2]", `[1]`},
+		{"NaN\\u12/[...'//c\n}null,[", `"NaN\\u12/[...'"`},
+	}
+
+	for _, tt := range tests {
+		result, err := RepairWithOptions(tt.input, opts)
+		if err != nil {
+			t.Fatalf("RepairWithOptions(%q) error = %v", tt.input, err)
+		}
+		if result != tt.expected {
+			t.Errorf("RepairWithOptions(%q) = %s, expected %s", tt.input, result, tt.expected)
+		}
+
+		var out bytes.Buffer
+		if err := RepairStream(&out, iotest.OneByteReader(strings.NewReader(tt.input)), opts); err != nil {
+			t.Fatalf("RepairStream(%q) error = %v", tt.input, err)
+		}
+		if out.String() != tt.expected {
+			t.Errorf("RepairStream(%q) = %s, expected %s", tt.input, out.String(), tt.expected)
+		}
+	}
+}
+
+func TestRepairStreamLargeDocument(t *testing.T) {
+	var input strings.Builder
+	input.WriteString("[\n")
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&input, "  {_id: ObjectId('%024d'), name: 'user %d', tags: ['a', 'b',],},\n", i, i)
+	}
+	input.WriteString("]")
+
+	expected, err := Repair(input.String())
+	if err != nil {
+		t.Fatalf("Repair() error = %v", err)
+	}
+
+	// Output must be written before the whole input was read
+	var w bytes.Buffer
+	r := &recordingReader{r: strings.NewReader(input.String()), w: &w}
+	if err := RepairStream(&w, r, DefaultOptions()); err != nil {
+		t.Fatalf("RepairStream() error = %v", err)
+	}
+
+	if w.String() != expected {
+		t.Errorf("RepairStream() output differs from Repair()")
+	}
+	if r.writtenBeforeLastRead == 0 {
+		t.Errorf("RepairStream() wrote no output before the end of input")
+	}
+}
+
+func TestRepairStreamRootValues(t *testing.T) {
+	var input strings.Builder
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&input, "{id: %d, name: 'user %d'}\n", i, i)
+	}
+
+	opts := DefaultOptions()
+	opts.TrailingContent = TrailingContentArray
+	expected, err := RepairWithOptions(input.String(), opts)
+	if err != nil {
+		t.Fatalf("RepairWithOptions() error = %v", err)
+	}
+
+	// Values after the first one are written before the whole input was read
+	var w bytes.Buffer
+	r := &recordingReader{r: strings.NewReader(input.String()), w: &w}
+	if err := RepairStream(&w, r, opts); err != nil {
+		t.Fatalf("RepairStream() error = %v", err)
+	}
+
+	if w.String() != expected {
+		t.Errorf("RepairStream() output differs from RepairWithOptions()")
+	}
+	if r.writtenBeforeLastRead == 0 {
+		t.Errorf("RepairStream() wrote no output before the end of input")
+	}
+}
+
+func TestRepairStreamError(t *testing.T) {
+	input := "[\n" + strings.Repeat("  {\"a\": 1},\n", 5000) + "  {\"a\": @}\n]"
+
+	err := RepairStream(io.Discard, strings.NewReader(input), DefaultOptions())
+
+	var repairErr *RepairError
+	if !errors.As(err, &repairErr) {
+		t.Fatalf("RepairStream() error = %v, expected a *RepairError", err)
+	}
+	if offset := strings.IndexByte(input, '@'); repairErr.Offset != offset {
+		t.Errorf("Offset = %d, expected %d", repairErr.Offset, offset)
+	}
+	if repairErr.Line != 5002 || repairErr.Column != 9 {
+		t.Errorf("position = %d:%d, expected 5002:9", repairErr.Line, repairErr.Column)
+	}
+}
+
+func TestRepairStreamDistantClosingQuote(t *testing.T) {
+	// The missing closing quote is only found after the first chunk, and the
+	// '@' is part of the string until then
+	input := `["abc, @` + strings.Repeat("y,", 20000) + `"]`
+
+	expected, err := Repair(input)
+	if err != nil {
+		t.Fatalf("Repair() error = %v", err)
+	}
+
+	var out bytes.Buffer
+	if err := RepairStream(&out, strings.NewReader(input), DefaultOptions()); err != nil {
+		t.Fatalf("RepairStream() error = %v", err)
+	}
+	if out.String() != expected {
+		t.Errorf("RepairStream() output differs from Repair()")
+	}
+}
+
+func TestRepairStreamReadError(t *testing.T) {
+	readErr := errors.New("read failed")
+	r := io.MultiReader(strings.NewReader(`{"a": [1, 2`), iotest.ErrReader(readErr))
+
+	if err := RepairStream(io.Discard, r, DefaultOptions()); !errors.Is(err, readErr) {
+		t.Errorf("RepairStream() error = %v, expected %v", err, readErr)
+	}
+}
+
+// recordingReader records how much output was written before the last read
+// of its input
+type recordingReader struct {
+	r                     io.Reader
+	w                     *bytes.Buffer
+	writtenBeforeLastRead int
+}
+
+func (r *recordingReader) Read(b []byte) (int, error) {
+	r.writtenBeforeLastRead = r.w.Len()
+	return r.r.Read(b)
+}